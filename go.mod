@@ -2,4 +2,10 @@ module pdf_wasm
 
 go 1.25.0
 
-require github.com/jung-kurt/gofpdf v1.16.2
+require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/jung-kurt/gofpdf v1.16.2
+	golang.org/x/image v0.25.0
+)
+
+require golang.org/x/text v0.23.0 // indirect
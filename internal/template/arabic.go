@@ -0,0 +1,157 @@
+package template
+
+import "strings"
+
+// --- Mise en forme de l'arabe (formes contextuelles) ---
+//
+// Une lettre arabe s'écrit différemment selon qu'elle est liée à la lettre précédente,
+// à la suivante, aux deux ou à aucune. Les polices TrueType portent ces variantes dans
+// leurs tables OpenType, que gofpdf n'applique pas : le texte est donc converti vers
+// les formes de présentation Unicode (bloc FE70-FEFF et FB50-FDFF pour le persan),
+// présentes dans les polices courantes (DejaVu, Noto Naskh, Amiri...). La conversion
+// se fait dans l'ordre logique, avant le réordonnancement bidi.
+
+// arabicForms donne les formes de présentation d'une lettre : isolée, finale, initiale, médiane.
+// Une lettre sans formes initiale et médiane ne se lie pas à la lettre suivante.
+var arabicForms = map[rune][4]rune{
+	0x0621: {0xFE80, 0, 0, 0},                // hamza
+	0x0622: {0xFE81, 0xFE82, 0, 0},           // alef madda
+	0x0623: {0xFE83, 0xFE84, 0, 0},           // alef hamza dessus
+	0x0624: {0xFE85, 0xFE86, 0, 0},           // waw hamza
+	0x0625: {0xFE87, 0xFE88, 0, 0},           // alef hamza dessous
+	0x0626: {0xFE89, 0xFE8A, 0xFE8B, 0xFE8C}, // yeh hamza
+	0x0627: {0xFE8D, 0xFE8E, 0, 0},           // alef
+	0x0628: {0xFE8F, 0xFE90, 0xFE91, 0xFE92}, // beh
+	0x0629: {0xFE93, 0xFE94, 0, 0},           // teh marbuta
+	0x062A: {0xFE95, 0xFE96, 0xFE97, 0xFE98}, // teh
+	0x062B: {0xFE99, 0xFE9A, 0xFE9B, 0xFE9C}, // theh
+	0x062C: {0xFE9D, 0xFE9E, 0xFE9F, 0xFEA0}, // jeem
+	0x062D: {0xFEA1, 0xFEA2, 0xFEA3, 0xFEA4}, // hah
+	0x062E: {0xFEA5, 0xFEA6, 0xFEA7, 0xFEA8}, // khah
+	0x062F: {0xFEA9, 0xFEAA, 0, 0},           // dal
+	0x0630: {0xFEAB, 0xFEAC, 0, 0},           // thal
+	0x0631: {0xFEAD, 0xFEAE, 0, 0},           // reh
+	0x0632: {0xFEAF, 0xFEB0, 0, 0},           // zain
+	0x0633: {0xFEB1, 0xFEB2, 0xFEB3, 0xFEB4}, // seen
+	0x0634: {0xFEB5, 0xFEB6, 0xFEB7, 0xFEB8}, // sheen
+	0x0635: {0xFEB9, 0xFEBA, 0xFEBB, 0xFEBC}, // sad
+	0x0636: {0xFEBD, 0xFEBE, 0xFEBF, 0xFEC0}, // dad
+	0x0637: {0xFEC1, 0xFEC2, 0xFEC3, 0xFEC4}, // tah
+	0x0638: {0xFEC5, 0xFEC6, 0xFEC7, 0xFEC8}, // zah
+	0x0639: {0xFEC9, 0xFECA, 0xFECB, 0xFECC}, // ain
+	0x063A: {0xFECD, 0xFECE, 0xFECF, 0xFED0}, // ghain
+	0x0641: {0xFED1, 0xFED2, 0xFED3, 0xFED4}, // feh
+	0x0642: {0xFED5, 0xFED6, 0xFED7, 0xFED8}, // qaf
+	0x0643: {0xFED9, 0xFEDA, 0xFEDB, 0xFEDC}, // kaf
+	0x0644: {0xFEDD, 0xFEDE, 0xFEDF, 0xFEE0}, // lam
+	0x0645: {0xFEE1, 0xFEE2, 0xFEE3, 0xFEE4}, // meem
+	0x0646: {0xFEE5, 0xFEE6, 0xFEE7, 0xFEE8}, // noon
+	0x0647: {0xFEE9, 0xFEEA, 0xFEEB, 0xFEEC}, // heh
+	0x0648: {0xFEED, 0xFEEE, 0, 0},           // waw
+	0x0649: {0xFEEF, 0xFEF0, 0, 0},           // alef maksura
+	0x064A: {0xFEF1, 0xFEF2, 0xFEF3, 0xFEF4}, // yeh
+	0x067E: {0xFB56, 0xFB57, 0xFB58, 0xFB59}, // peh (persan)
+	0x0686: {0xFB7A, 0xFB7B, 0xFB7C, 0xFB7D}, // tcheh (persan)
+	0x0698: {0xFB8A, 0xFB8B, 0, 0},           // jeh (persan)
+	0x06A9: {0xFB8E, 0xFB8F, 0xFB90, 0xFB91}, // keheh (persan)
+	0x06AF: {0xFB92, 0xFB93, 0xFB94, 0xFB95}, // gaf (persan)
+	0x06CC: {0xFBFC, 0xFBFD, 0xFBFE, 0xFBFF}, // yeh farsi
+}
+
+// lamAlef donne les ligatures lam-alef (isolée, finale) selon l'alef qui suit le lam
+var lamAlef = map[rune][2]rune{
+	0x0622: {0xFEF5, 0xFEF6},
+	0x0623: {0xFEF7, 0xFEF8},
+	0x0625: {0xFEF9, 0xFEFA},
+	0x0627: {0xFEFB, 0xFEFC},
+}
+
+const tatweel = 0x0640 // trait d'allongement, lié des deux côtés
+
+// joinsNext indique si un caractère se lie au caractère qui le suit
+func joinsNext(r rune) bool {
+	if r == tatweel {
+		return true
+	}
+	forms, ok := arabicForms[r]
+	return ok && forms[2] != 0
+}
+
+// joinsPrevious indique si un caractère se lie au caractère qui le précède
+func joinsPrevious(r rune) bool {
+	if r == tatweel {
+		return true
+	}
+	forms, ok := arabicForms[r]
+	return ok && forms[1] != 0
+}
+
+// isTransparent indique si un caractère est ignoré pour la liaison (voyelles et signes diacritiques)
+func isTransparent(r rune) bool {
+	return classOf(r) == bidiNSM
+}
+
+// shapeArabic remplace les lettres arabes d'un texte par leurs formes contextuelles
+func shapeArabic(text string) string {
+	if !strings.ContainsFunc(text, func(r rune) bool { return r >= 0x0600 && r <= 0x06FF }) {
+		return text
+	}
+
+	runes := []rune(text)
+	shaped := make([]rune, 0, len(runes))
+
+	// neighbour retourne la lettre voisine dans la direction step, voyelles ignorées
+	neighbour := func(i, step int) rune {
+		for j := i + step; j >= 0 && j < len(runes); j += step {
+			if !isTransparent(runes[j]) {
+				return runes[j]
+			}
+		}
+		return 0
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		forms, ok := arabicForms[r]
+		if !ok {
+			shaped = append(shaped, r)
+			continue
+		}
+
+		prev := neighbour(i, -1)
+		linkedBefore := joinsNext(prev) && joinsPrevious(r)
+
+		// Lam suivi d'un alef : ligature
+		if r == 0x0644 {
+			j := i + 1
+			for j < len(runes) && isTransparent(runes[j]) {
+				j++
+			}
+			if j < len(runes) {
+				if ligature, ok := lamAlef[runes[j]]; ok {
+					if linkedBefore {
+						shaped = append(shaped, ligature[1])
+					} else {
+						shaped = append(shaped, ligature[0])
+					}
+					shaped = append(shaped, runes[i+1:j]...)
+					i = j
+					continue
+				}
+			}
+		}
+
+		linkedAfter := joinsNext(r) && joinsPrevious(neighbour(i, 1))
+		switch {
+		case linkedBefore && linkedAfter:
+			shaped = append(shaped, forms[3])
+		case linkedAfter:
+			shaped = append(shaped, forms[2])
+		case linkedBefore:
+			shaped = append(shaped, forms[1])
+		default:
+			shaped = append(shaped, forms[0])
+		}
+	}
+	return string(shaped)
+}
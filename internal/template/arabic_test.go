@@ -0,0 +1,34 @@
+package template
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestShapeArabic(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []rune
+	}{
+		{"isolée", "ب", []rune{0xFE8F}},
+		{"initiale et finale", "بب", []rune{0xFE91, 0xFE90}},
+		{"médiane", "ببب", []rune{0xFE91, 0xFE92, 0xFE90}},
+		{"alef ne se lie pas au suivant", "اب", []rune{0xFE8D, 0xFE8F}},
+		{"alef final", "با", []rune{0xFE91, 0xFE8E}},
+		{"voyelle ignorée pour la liaison", "بَب", []rune{0xFE91, 0x064E, 0xFE90}},
+		{"tatweel", "بـ", []rune{0xFE91, 0x0640}},
+		{"lam-alef isolé", "لا", []rune{0xFEFB}},
+		{"lam-alef final", "بلا", []rune{0xFE91, 0xFEFC}},
+		{"lam-alef hamza", "لأ", []rune{0xFEF7}},
+		{"mot", "سلام", []rune{0xFEB3, 0xFEFC, 0xFEE1}},
+		{"mot terminé par ya", "عربي", []rune{0xFECB, 0xFEAE, 0xFE91, 0xFEF2}},
+		{"mots séparés", "ب ب", []rune{0xFE8F, ' ', 0xFE8F}},
+		{"texte latin inchangé", "abc", []rune("abc")},
+	}
+	for _, test := range tests {
+		if got := []rune(shapeArabic(test.text)); !slices.Equal(got, test.want) {
+			t.Errorf("%s : %U, attendu %U", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+package template
+
+import (
+	"strings"
+	"unicode"
+)
+
+// --- Texte bidirectionnel (algorithme bidi Unicode) ---
+//
+// Les textes arabes et hébreux sont stockés dans l'ordre logique (ordre de lecture) et
+// doivent être réordonnés ligne par ligne pour l'affichage. L'implémentation suit
+// l'algorithme bidi Unicode (UAX #9) sans les caractères de contrôle explicites
+// (plongements et isolats) : résolution des types faibles (W1-W7) et neutres
+// (N1-N2), niveaux implicites (I1-I2), puis inversion (L1-L2) et miroirs (L4). Les paires
+// de crochets (N0) ne sont pas appariées : ce sont des neutres ordinaires.
+// Seules les polices UTF-8 peuvent afficher ces écritures ; le texte arabe y est aussi
+// mis en forme (voir arabic.go).
+
+// bidiClass est le type bidi d'un caractère
+type bidiClass uint8
+
+const (
+	bidiL   bidiClass = iota // gauche à droite
+	bidiR                    // droite à gauche (hébreu)
+	bidiAL                   // lettre arabe
+	bidiEN                   // chiffre européen
+	bidiES                   // séparateur de nombres (+ -)
+	bidiET                   // terminateur de nombre (% € #)
+	bidiAN                   // chiffre arabe
+	bidiCS                   // séparateur commun (, . : /)
+	bidiNSM                  // marque non espaçante
+	bidiBN                   // neutre de frontière (caractères de contrôle)
+	bidiB                    // séparateur de paragraphe
+	bidiS                    // séparateur de segment (tabulation)
+	bidiWS                   // espace
+	bidiON                   // autre neutre
+)
+
+// classOf retourne le type bidi d'un caractère (approximation des données Unicode par plages)
+func classOf(r rune) bidiClass {
+	switch {
+	case r >= '0' && r <= '9', r >= 0x06F0 && r <= 0x06F9, r == 0xB2, r == 0xB3, r == 0xB9:
+		return bidiEN
+	case r == '+' || r == '-' || r == 0x2212:
+		return bidiES
+	case r == ',' || r == '.' || r == '/' || r == ':' || r == 0xA0 || r == 0x060C || r == 0x202F:
+		return bidiCS
+	case r == '#' || r == '%' || r == 0xB0 || r == 0xB1 || r == 0x2030 || r == 0x2031 || r == 0x066A ||
+		unicode.Is(unicode.Sc, r):
+		return bidiET
+	case r == '\n' || r == '\r' || r == 0x1C || r == 0x1D || r == 0x1E || r == 0x85 || r == 0x2029:
+		return bidiB
+	case r == '\t' || r == 0x0B || r == 0x1F:
+		return bidiS
+	case r == 0x200E:
+		return bidiL
+	case r == 0x200F:
+		return bidiR
+	case r == 0x061C:
+		return bidiAL
+	case r == 0x0C || unicode.Is(unicode.Zs, r):
+		return bidiWS
+	case r >= 0x0660 && r <= 0x0669, r == 0x066B, r == 0x066C, r >= 0x0600 && r <= 0x0605, r == 0x06DD:
+		return bidiAN
+	case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r):
+		return bidiNSM
+	case unicode.Is(unicode.Cc, r) || unicode.Is(unicode.Cf, r):
+		return bidiBN
+	case r >= 0x0590 && r <= 0x05FF, r >= 0x07C0 && r <= 0x085F, r >= 0xFB1D && r <= 0xFB4F,
+		r >= 0x10800 && r <= 0x10FFF, r >= 0x1E800 && r <= 0x1EDFF:
+		return bidiR
+	case r >= 0x0600 && r <= 0x07BF, r >= 0x0860 && r <= 0x08FF, r >= 0xFB50 && r <= 0xFDFF,
+		r >= 0xFE70 && r <= 0xFEFF, r >= 0x1EE00 && r <= 0x1EEFF:
+		return bidiAL
+	case unicode.IsLetter(r) || unicode.Is(unicode.Mc, r) || unicode.IsDigit(r):
+		return bidiL
+	}
+	return bidiON
+}
+
+// isRTL indique si un caractère s'écrit de droite à gauche
+func isRTL(r rune) bool {
+	class := classOf(r)
+	return class == bidiR || class == bidiAL
+}
+
+// containsRTL indique si un texte contient des caractères de droite à gauche
+func containsRTL(text string) bool {
+	for _, r := range text {
+		if isRTL(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseLevel retourne le niveau de paragraphe : 1 pour "rtl", 0 pour "ltr", sinon
+// selon le premier caractère fort du texte (règles P2-P3)
+func baseLevel(direction string, text []rune) int {
+	switch direction {
+	case "rtl":
+		return 1
+	case "ltr":
+		return 0
+	}
+	for _, r := range text {
+		switch classOf(r) {
+		case bidiL:
+			return 0
+		case bidiR, bidiAL:
+			return 1
+		}
+	}
+	return 0
+}
+
+// bidiLevels résout le niveau d'imbrication de chaque caractère d'une ligne
+func bidiLevels(text []rune, base int) []int {
+	n := len(text)
+	types := make([]bidiClass, n)
+	for i, r := range text {
+		types[i] = classOf(r)
+	}
+
+	sos := bidiL
+	if base%2 == 1 {
+		sos = bidiR
+	}
+
+	// W1 : une marque non espaçante prend le type du caractère précédent (sos en début de texte)
+	for i := range types {
+		if types[i] == bidiNSM {
+			if i == 0 {
+				types[i] = sos
+			} else {
+				types[i] = types[i-1]
+			}
+		}
+	}
+
+	// W2 : un chiffre européen après une lettre arabe devient chiffre arabe ; W3 : AL devient R
+	last := sos
+	for i, t := range types {
+		switch t {
+		case bidiL, bidiR, bidiAL:
+			last = t
+		case bidiEN:
+			if last == bidiAL {
+				types[i] = bidiAN
+			}
+		}
+	}
+	for i, t := range types {
+		if t == bidiAL {
+			types[i] = bidiR
+		}
+	}
+
+	// W4 : un séparateur seul entre deux nombres du même type prend ce type
+	for i := 1; i+1 < n; i++ {
+		prev, next := types[i-1], types[i+1]
+		switch {
+		case types[i] == bidiES && prev == bidiEN && next == bidiEN:
+			types[i] = bidiEN
+		case types[i] == bidiCS && prev == next && (prev == bidiEN || prev == bidiAN):
+			types[i] = prev
+		}
+	}
+
+	// W5 : des terminateurs contigus à un chiffre européen deviennent chiffres européens
+	for i := 0; i < n; i++ {
+		if types[i] != bidiET {
+			continue
+		}
+		j := i
+		for j < n && types[j] == bidiET {
+			j++
+		}
+		if (i > 0 && types[i-1] == bidiEN) || (j < n && types[j] == bidiEN) {
+			for k := i; k < j; k++ {
+				types[k] = bidiEN
+			}
+		}
+		i = j - 1
+	}
+
+	// W6 : les séparateurs et terminateurs restants sont neutres
+	for i, t := range types {
+		if t == bidiES || t == bidiET || t == bidiCS || t == bidiBN {
+			types[i] = bidiON
+		}
+	}
+
+	// W7 : un chiffre européen précédé d'une lettre gauche à droite devient L
+	last = sos
+	for i, t := range types {
+		switch t {
+		case bidiL, bidiR:
+			last = t
+		case bidiEN:
+			if last == bidiL {
+				types[i] = bidiL
+			}
+		}
+	}
+
+	// N1-N2 : une suite de neutres prend la direction du texte qui l'entoure si elle est la même
+	// des deux côtés (les chiffres comptent comme R), sinon celle du paragraphe
+	strong := func(t bidiClass) bidiClass {
+		if t == bidiEN || t == bidiAN {
+			return bidiR
+		}
+		return t
+	}
+	isNeutral := func(t bidiClass) bool {
+		return t == bidiB || t == bidiS || t == bidiWS || t == bidiON
+	}
+	for i := 0; i < n; i++ {
+		if !isNeutral(types[i]) {
+			continue
+		}
+		j := i
+		for j < n && isNeutral(types[j]) {
+			j++
+		}
+		before, after := sos, sos
+		if i > 0 {
+			before = strong(types[i-1])
+		}
+		if j < n {
+			after = strong(types[j])
+		}
+		resolved := sos
+		if before == after {
+			resolved = before
+		}
+		for k := i; k < j; k++ {
+			types[k] = resolved
+		}
+		i = j - 1
+	}
+
+	// I1-I2 : niveaux implicites
+	levels := make([]int, n)
+	for i, t := range types {
+		level := base
+		if base%2 == 0 {
+			switch t {
+			case bidiR:
+				level++
+			case bidiAN, bidiEN:
+				level += 2
+			}
+		} else if t == bidiL || t == bidiEN || t == bidiAN {
+			level++
+		}
+		levels[i] = level
+	}
+
+	// L1 : les séparateurs de segment et de paragraphe, ainsi que les espaces qui les précèdent
+	// ou qui terminent la ligne, reviennent au niveau du paragraphe
+	reset := true
+	for i := n - 1; i >= 0; i-- {
+		switch classOf(text[i]) {
+		case bidiS, bidiB:
+			reset = true
+			levels[i] = base
+		case bidiWS, bidiBN:
+			if reset {
+				levels[i] = base
+			}
+		default:
+			reset = false
+		}
+	}
+	return levels
+}
+
+// visualOrder retourne les indices des caractères dans l'ordre d'affichage (règle L2) :
+// du plus haut niveau au plus bas niveau impair, chaque suite de niveau supérieur ou égal est inversée
+func visualOrder(levels []int) []int {
+	order := make([]int, len(levels))
+	highest, lowestOdd := 0, 1<<30
+	for i, level := range levels {
+		order[i] = i
+		if level > highest {
+			highest = level
+		}
+		if level%2 == 1 && level < lowestOdd {
+			lowestOdd = level
+		}
+	}
+
+	for level := highest; level >= lowestOdd; level-- {
+		for i := 0; i < len(order); i++ {
+			if levels[order[i]] < level {
+				continue
+			}
+			j := i
+			for j < len(order) && levels[order[j]] >= level {
+				j++
+			}
+			for a, z := i, j-1; a < z; a, z = a+1, z-1 {
+				order[a], order[z] = order[z], order[a]
+			}
+			i = j
+		}
+	}
+	return order
+}
+
+// bidiMirrors associe les caractères à leur miroir, affiché dans un passage de droite à gauche (L4)
+var bidiMirrors = map[rune]rune{
+	'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{', '<': '>', '>': '<',
+	'«': '»', '»': '«', '‹': '›', '›': '‹', '≤': '≥', '≥': '≤',
+}
+
+// bidiApplies indique si un texte doit être mis en forme et réordonné avec la police courante
+func (b *PDFBuilder) bidiApplies(text, direction string) bool {
+	return b.utf8Fonts[strings.ToLower(b.font.family)] && (direction == "rtl" || containsRTL(text))
+}
+
+// visualText met en forme et réordonne un texte d'une seule ligne pour la police courante
+// (cellules de tableau, textes sur une ligne) ; les polices 8 bits reçoivent le texte traduit
+func (b *PDFBuilder) visualText(text, direction string) string {
+	if !b.bidiApplies(text, direction) {
+		return b.encode(text)
+	}
+
+	runes := []rune(shapeArabic(text))
+	levels := bidiLevels(runes, baseLevel(direction, runes))
+
+	var sb strings.Builder
+	for _, i := range visualOrder(levels) {
+		r := runes[i]
+		if levels[i]%2 == 1 {
+			if mirror, ok := bidiMirrors[r]; ok {
+				r = mirror
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// directionAlign convertit un alignement en alignement gofpdf : sans alignement explicite,
+// un texte de droite à gauche est aligné à droite
+func directionAlign(align, direction string) string {
+	if align == "" && direction == "rtl" {
+		return "R"
+	}
+	return cellAlign(align)
+}
+
+// setDirection détermine le sens des lignes d'un paragraphe de texte riche : celui du style,
+// sinon celui du premier caractère fort du paragraphe
+func (b *PDFBuilder) setDirection(runs []textRun, lines []richLine) {
+	direction := ""
+	if len(runs) > 0 {
+		direction = runs[0].style.Direction
+	}
+
+	var text []rune
+	if direction == "" {
+		for _, line := range lines {
+			lineText, _ := b.lineText(runs, line.fragments)
+			text = append(text, lineText...)
+		}
+	}
+
+	rtl := baseLevel(direction, text) == 1
+	for i := range lines {
+		lines[i].rtl = rtl
+	}
+}
+
+// lineText retourne les caractères d'une ligne de texte riche et, pour chacun, l'indice de son
+// morceau ; le texte des polices standard, déjà traduit, est lu octet par octet
+func (b *PDFBuilder) lineText(runs []textRun, fragments []richFragment) (text []rune, owners []int) {
+	for f, fragment := range fragments {
+		if b.isUTF8Style(runs[fragment.run].style) {
+			for _, r := range fragment.text {
+				text = append(text, r)
+				owners = append(owners, f)
+			}
+			continue
+		}
+		for i := 0; i < len(fragment.text); i++ {
+			text = append(text, rune(fragment.text[i]))
+			owners = append(owners, f)
+		}
+	}
+	return text, owners
+}
+
+// visualFragments réordonne les morceaux d'une ligne de texte riche pour l'affichage : les
+// caractères sont réordonnés sur toute la ligne puis regroupés par morceau d'origine (un
+// morceau mêlant les deux directions est découpé), et les largeurs sont recalculées.
+// Les morceaux sont retournés inchangés si la ligne ne contient que du texte de gauche à droite.
+func (b *PDFBuilder) visualFragments(runs []textRun, fragments []richFragment, base int) []richFragment {
+	text, owners := b.lineText(runs, fragments)
+	if base == 0 && !containsRTL(string(text)) {
+		return fragments
+	}
+	levels := bidiLevels(text, base)
+
+	saved := b.font
+	defer func() {
+		if saved.family != "" {
+			b.setFont(saved.family, saved.style, saved.size)
+		}
+	}()
+
+	var visual []richFragment
+	var sb strings.Builder
+	current, currentLevel := -1, -1
+	flush := func() {
+		if current < 0 {
+			return
+		}
+		fragment := fragments[current]
+		style := runs[fragment.run].style
+		b.applyFont(style)
+		fragment.text = sb.String()
+		fragment.width = b.pdf.GetStringWidth(fragment.text) + style.LetterSpacing*float64(b.glyphCount(fragment.text))
+		if fragment.space {
+			fragment.width += style.WordSpacing * float64(strings.Count(fragment.text, " "))
+		}
+		visual = append(visual, fragment)
+		sb.Reset()
+	}
+
+	for _, i := range visualOrder(levels) {
+		if owners[i] != current || levels[i] != currentLevel {
+			flush()
+			current, currentLevel = owners[i], levels[i]
+		}
+		r := text[i]
+		if levels[i]%2 == 1 {
+			if mirror, ok := bidiMirrors[r]; ok {
+				r = mirror
+			}
+		}
+		if b.isUTF8Style(runs[fragments[current].run].style) {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte(byte(r))
+		}
+	}
+	flush()
+	return visual
+}
+
+// isUTF8Style indique si un style utilise une police UTF-8
+func (b *PDFBuilder) isUTF8Style(style *Style) bool {
+	font := b.config.Fonts.Default
+	if style != nil && style.Font != "" {
+		font = style.Font
+	}
+	return b.utf8Fonts[strings.ToLower(font)]
+}
@@ -0,0 +1,162 @@
+package template
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// bidiCharacterTests suit le format de BidiCharacterTest.txt (Unicode) : points de code ;
+// direction du paragraphe (0 ltr, 1 rtl, 2 auto) ; niveau du paragraphe ; niveaux résolus ;
+// ordre visuel. Les cas se limitent aux règles implémentées (W1-W7, N1-N2, I1-I2, L1-L2) :
+// ni caractères de contrôle explicites, ni paires de crochets (N0). Les résultats attendus
+// ont été calculés avec une implémentation complète d'UAX #9 (golang.org/x/text/unicode/bidi).
+var bidiCharacterTests = []string{
+	// W1 : marque non espaçante (U+0300, après une lettre, un espace, une tabulation, en début de texte)
+	"0061 0300 0020 05D0;0;0;0 0 0 1;0 1 2 3",
+	"0061 0300 0020 05D0;1;1;2 2 1 1;3 2 0 1",
+	"0061 0300 0020 05D0;2;0;0 0 0 1;0 1 2 3",
+	"05D0 0300 0020 0061;0;0;1 1 0 0;1 0 2 3",
+	"05D0 0300 0020 0061;1;1;1 1 1 2;3 2 1 0",
+	"05D0 0300 0020 0061;2;1;1 1 1 2;3 2 1 0",
+	"0300 0061;0;0;0 0;0 1",
+	"0300 0061;1;1;1 2;1 0",
+	"0300 0061;2;0;0 0;0 1",
+	"0300 05D0;0;0;0 1;0 1",
+	"0300 05D0;1;1;1 1;1 0",
+	"0300 05D0;2;1;1 1;1 0",
+	"05D0 0020 0300 0061;0;0;1 0 0 0;0 1 2 3",
+	"05D0 0020 0300 0061;1;1;1 1 1 2;3 2 1 0",
+	"05D0 0020 0300 0061;2;1;1 1 1 2;3 2 1 0",
+	"0061 0020 0300 05D0;0;0;0 0 0 1;0 1 2 3",
+	"0061 0020 0300 05D0;1;1;2 1 1 1;3 2 1 0",
+	"0061 0020 0300 05D0;2;0;0 0 0 1;0 1 2 3",
+	"05D0 0009 0300 0061;0;0;1 0 0 0;0 1 2 3",
+	"05D0 0009 0300 0061;1;1;1 1 1 2;3 2 1 0",
+	"05D0 0009 0300 0061;2;1;1 1 1 2;3 2 1 0",
+	"0627 0300 0031;0;0;1 1 2;2 1 0",
+	"0627 0300 0031;1;1;1 1 2;2 1 0",
+	"0627 0300 0031;2;1;1 1 2;2 1 0",
+
+	// W2-W3 : chiffre européen après une lettre arabe, lettre arabe
+	"0627 0020 0031 0032;0;0;1 1 2 2;2 3 1 0",
+	"0627 0020 0031 0032;1;1;1 1 2 2;2 3 1 0",
+	"0627 0020 0031 0032;2;1;1 1 2 2;2 3 1 0",
+	"0061 0020 0627 0031;0;0;0 0 1 2;0 1 3 2",
+	"0061 0020 0627 0031;1;1;2 1 1 2;3 2 1 0",
+	"0061 0020 0627 0031;2;0;0 0 1 2;0 1 3 2",
+	"05D0 0020 0031 0032;0;0;1 1 2 2;2 3 1 0",
+	"05D0 0020 0031 0032;1;1;1 1 2 2;2 3 1 0",
+	"05D0 0020 0031 0032;2;1;1 1 2 2;2 3 1 0",
+
+	// W4 : séparateur seul entre deux nombres
+	"05D0 0020 0031 002B 0032;0;0;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0031 002B 0032;1;1;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0031 002B 0032;2;1;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0031 002C 0032;0;0;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0031 002C 0032;1;1;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0031 002C 0032;2;1;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0661 002C 0662;0;0;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0661 002C 0662;1;1;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0661 002C 0662;2;1;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0661 002C 0031;0;0;1 1 2 1 2;4 3 2 1 0",
+	"05D0 0020 0661 002C 0031;1;1;1 1 2 1 2;4 3 2 1 0",
+	"05D0 0020 0661 002C 0031;2;1;1 1 2 1 2;4 3 2 1 0",
+	"05D0 0020 0031 002B 002B 0032;0;0;1 1 2 1 1 2;5 4 3 2 1 0",
+	"05D0 0020 0031 002B 002B 0032;1;1;1 1 2 1 1 2;5 4 3 2 1 0",
+	"05D0 0020 0031 002B 002B 0032;2;1;1 1 2 1 1 2;5 4 3 2 1 0",
+	"0627 0020 0031 002C 0032;0;0;1 1 2 2 2;2 3 4 1 0",
+	"0627 0020 0031 002C 0032;1;1;1 1 2 2 2;2 3 4 1 0",
+	"0627 0020 0031 002C 0032;2;1;1 1 2 2 2;2 3 4 1 0",
+
+	// W5-W6 : terminateurs contigus à un chiffre européen, séparateurs restants
+	"05D0 0020 0024 0031;0;0;1 1 2 2;2 3 1 0",
+	"05D0 0020 0024 0031;1;1;1 1 2 2;2 3 1 0",
+	"05D0 0020 0024 0031;2;1;1 1 2 2;2 3 1 0",
+	"05D0 0020 0031 0025 0025;0;0;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0031 0025 0025;1;1;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0031 0025 0025;2;1;1 1 2 2 2;2 3 4 1 0",
+	"05D0 0020 0025 0020 05D1;0;0;1 1 1 1 1;4 3 2 1 0",
+	"05D0 0020 0025 0020 05D1;1;1;1 1 1 1 1;4 3 2 1 0",
+	"05D0 0020 0025 0020 05D1;2;1;1 1 1 1 1;4 3 2 1 0",
+	"05D0 0020 0661 0025;0;0;1 1 2 0;2 1 0 3",
+	"05D0 0020 0661 0025;1;1;1 1 2 1;3 2 1 0",
+	"05D0 0020 0661 0025;2;1;1 1 2 1;3 2 1 0",
+
+	// W7 : chiffre européen après une lettre gauche à droite
+	"0061 0020 0031 0032;0;0;0 0 0 0;0 1 2 3",
+	"0061 0020 0031 0032;1;1;2 2 2 2;0 1 2 3",
+	"0061 0020 0031 0032;2;0;0 0 0 0;0 1 2 3",
+	"0061 0020 05D0 0020 0031;0;0;0 0 1 1 2;0 1 4 3 2",
+	"0061 0020 05D0 0020 0031;1;1;2 1 1 1 2;4 3 2 1 0",
+	"0061 0020 05D0 0020 0031;2;0;0 0 1 1 2;0 1 4 3 2",
+	"0031 0032 0020 05D0;0;0;0 0 0 1;0 1 2 3",
+	"0031 0032 0020 05D0;1;1;2 2 1 1;3 2 0 1",
+	"0031 0032 0020 05D0;2;1;2 2 1 1;3 2 0 1",
+
+	// N1-N2 : neutres entre deux directions (les chiffres comptent comme R)
+	"05D0 0020 0021 0020 05D1;0;0;1 1 1 1 1;4 3 2 1 0",
+	"05D0 0020 0021 0020 05D1;1;1;1 1 1 1 1;4 3 2 1 0",
+	"05D0 0020 0021 0020 05D1;2;1;1 1 1 1 1;4 3 2 1 0",
+	"0061 0020 0021 0020 05D0;0;0;0 0 0 0 1;0 1 2 3 4",
+	"0061 0020 0021 0020 05D0;1;1;2 1 1 1 1;4 3 2 1 0",
+	"0061 0020 0021 0020 05D0;2;0;0 0 0 0 1;0 1 2 3 4",
+	"05D0 0020 0031 0020 05D1;0;0;1 1 2 1 1;4 3 2 1 0",
+	"05D0 0020 0031 0020 05D1;1;1;1 1 2 1 1;4 3 2 1 0",
+	"05D0 0020 0031 0020 05D1;2;1;1 1 2 1 1;4 3 2 1 0",
+	"0031 0020 05D0;0;0;0 0 1;0 1 2",
+	"0031 0020 05D0;1;1;2 1 1;2 1 0",
+	"0031 0020 05D0;2;1;2 1 1;2 1 0",
+	"0021 0021;0;0;0 0;0 1",
+	"0021 0021;1;1;1 1;1 0",
+	"0021 0021;2;0;0 0;0 1",
+
+	// I1-I2, L1-L2 : niveaux implicites, espaces de fin de ligne et avant tabulation, inversion
+	"0061 0062 0020 05D0 05D1 0020 0031 0032 0020 0063;0;0;0 0 0 1 1 1 2 2 0 0;0 1 2 6 7 5 4 3 8 9",
+	"0061 0062 0020 05D0 05D1 0020 0031 0032 0020 0063;1;1;2 2 1 1 1 1 2 2 1 2;9 8 6 7 5 4 3 2 0 1",
+	"0061 0062 0020 05D0 05D1 0020 0031 0032 0020 0063;2;0;0 0 0 1 1 1 2 2 0 0;0 1 2 6 7 5 4 3 8 9",
+	"05D0 0020 0061 0062 0020 0031 0032 0020 05D1;0;0;1 0 0 0 0 0 0 0 1;0 1 2 3 4 5 6 7 8",
+	"05D0 0020 0061 0062 0020 0031 0032 0020 05D1;1;1;1 1 2 2 2 2 2 1 1;8 7 2 3 4 5 6 1 0",
+	"05D0 0020 0061 0062 0020 0031 0032 0020 05D1;2;1;1 1 2 2 2 2 2 1 1;8 7 2 3 4 5 6 1 0",
+	"05D0 05D1 0020 0020;0;0;1 1 0 0;1 0 2 3",
+	"05D0 05D1 0020 0020;1;1;1 1 1 1;3 2 1 0",
+	"05D0 05D1 0020 0020;2;1;1 1 1 1;3 2 1 0",
+	"0061 0020 05D0 0020 0009 05D1 0020 0061;0;0;0 0 1 0 0 1 0 0;0 1 2 3 4 5 6 7",
+	"0061 0020 05D0 0020 0009 05D1 0020 0061;1;1;2 1 1 1 1 1 1 2;7 6 5 4 3 2 1 0",
+	"0061 0020 05D0 0020 0009 05D1 0020 0061;2;0;0 0 1 0 0 1 0 0;0 1 2 3 4 5 6 7",
+	"0627 0644 0633 0639 0631 0020 0661 0662 0663 002E 0664 0020 062F;0;0;1 1 1 1 1 1 2 2 2 2 2 1 1;12 11 6 7 8 9 10 5 4 3 2 1 0",
+	"0627 0644 0633 0639 0631 0020 0661 0662 0663 002E 0664 0020 062F;1;1;1 1 1 1 1 1 2 2 2 2 2 1 1;12 11 6 7 8 9 10 5 4 3 2 1 0",
+	"0627 0644 0633 0639 0631 0020 0661 0662 0663 002E 0664 0020 062F;2;1;1 1 1 1 1 1 2 2 2 2 2 1 1;12 11 6 7 8 9 10 5 4 3 2 1 0",
+}
+
+func TestBidiCharacters(t *testing.T) {
+	directions := []string{"ltr", "rtl", ""}
+	for _, line := range bidiCharacterTests {
+		fields := strings.Split(line, ";")
+		var text []rune
+		for _, code := range strings.Fields(fields[0]) {
+			r, err := strconv.ParseUint(code, 16, 32)
+			if err != nil {
+				t.Fatalf("%q : %v", line, err)
+			}
+			text = append(text, rune(r))
+		}
+		dir, _ := strconv.Atoi(fields[1])
+
+		base := baseLevel(directions[dir], text)
+		levels := bidiLevels(text, base)
+		got := fmt.Sprintf("%d;%s;%s", base, joinInts(levels), joinInts(visualOrder(levels)))
+		if want := strings.Join(fields[2:], ";"); got != want {
+			t.Errorf("%s;%s : obtenu %s, attendu %s", fields[0], fields[1], got, want)
+		}
+	}
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = strconv.Itoa(value)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,140 @@
+package template
+
+// --- Contenus convertis en blocs (markdown, html) ---
+//
+// Les éléments markdown et html sont convertis en éléments existants (textes riches,
+// tableaux, lignes) ; ils sont ensuite mesurés, mis en page et dessinés comme une
+// suite d'éléments dans la zone de contenu de l'élément.
+
+// blockStyles calcule les styles des blocs convertis à partir du style de l'élément
+type blockStyles struct {
+	base       *Style  // style de l'élément sans marges ni contraintes de pagination
+	size       float64 // taille du texte courant (pt)
+	lineHeight float64 // interligne du texte courant (mm)
+	gap        float64 // espace après un bloc (mm)
+}
+
+func newBlockStyles(style *Style) blockStyles {
+	base := mergeStyle(style, nil)
+	base.Margin, base.Padding = nil, nil
+	base.BreakBefore, base.KeepTogether, base.KeepWithNext = false, false, false
+	base.Fit = nil
+
+	blocks := blockStyles{base: base, size: 10}
+	if base.Size > 0 {
+		blocks.size = base.Size
+	}
+	blocks.lineHeight = textLineHeight(base, blocks.size*0.3528*1.5) // interligne de 1,5 par défaut (pt -> mm)
+	blocks.gap = blocks.lineHeight / 2
+	return blocks
+}
+
+// withSize retourne une copie du style de base dont la taille est multipliée par factor
+func (bs blockStyles) withSize(factor float64) *Style {
+	style := mergeStyle(bs.base, nil)
+	style.Size = bs.size * factor
+	return style
+}
+
+// paragraph construit un bloc de texte riche, retrait gauche left (mm), interligne proportionnel à la taille
+func (bs blockStyles) paragraph(spans []TextSpan, style *Style, left float64) Element {
+	style.Height = bs.lineHeight * style.Size / bs.size
+	style.Margin = []float64{0, 0, bs.gap, left}
+	return Element{Type: "text", Spans: spans, Style: style}
+}
+
+// heading construit un titre de niveau 1 à 6, gardé avec le bloc suivant
+func (bs blockStyles) heading(level int, spans []TextSpan, first bool) Element {
+	factors := []float64{1.8, 1.5, 1.25, 1.1, 1, 1}
+	if level < 1 {
+		level = 1
+	}
+	if level > len(factors) {
+		level = len(factors)
+	}
+
+	style := bs.withSize(factors[level-1])
+	style.Bold = true
+	style.KeepWithNext = true
+
+	heading := bs.paragraph(spans, style, 0)
+	if !first {
+		heading.Style.Margin[0] = bs.gap
+	}
+	return heading
+}
+
+// quote construit une citation : italique, gris par défaut, en retrait
+func (bs blockStyles) quote(spans []TextSpan) Element {
+	style := bs.withSize(1)
+	style.Italic = true
+	if style.Color == "" {
+		style.Color = "#555555"
+	}
+	return bs.paragraph(spans, style, 6)
+}
+
+// listItem construit une liste d'un seul élément, marqueur donné, en retrait selon le niveau :
+// les lignes suivantes s'alignent sur la première
+func (bs blockStyles) listItem(marker string, level int, spans []TextSpan) Element {
+	text := bs.paragraph(spans, bs.withSize(1), 0)
+	text.Style.Margin = nil
+
+	style := bs.withSize(1)
+	style.Margin = []float64{0, 0, bs.gap / 2, float64(level) * listIndent}
+	return Element{Type: "list", Bullet: marker, Items: []interface{}{[]Element{text}}, Style: style}
+}
+
+// endList rétablit l'espace normal après le dernier élément d'une liste
+func (bs blockStyles) endList(elements []Element) {
+	if n := len(elements); n > 0 && elements[n-1].Style != nil && len(elements[n-1].Style.Margin) == 4 {
+		elements[n-1].Style.Margin[2] = bs.gap
+	}
+}
+
+// end supprime l'espace après le dernier bloc
+func (bs blockStyles) end(elements []Element) {
+	if n := len(elements); n > 0 && elements[n-1].Style != nil && len(elements[n-1].Style.Margin) == 4 {
+		elements[n-1].Style.Margin[2] = 0
+	}
+}
+
+// blockElements convertit un élément markdown ou html en éléments pour la largeur donnée
+func blockElements(element Element, width float64) []Element {
+	switch element.Type {
+	case "markdown":
+		return markdownElements(element, width)
+	case "html":
+		return htmlElements(element)
+	}
+	return nil
+}
+
+// renderBlocks dessine un élément converti à la position courante (enfant de grille ou de cadre)
+func (b *PDFBuilder) renderBlocks(element Element) {
+	contentWidth, leftOffset := b.getContentArea(b.area.width, element.Style)
+	b.withArea(b.area.x+leftOffset, contentWidth, func() {
+		for _, child := range blockElements(element, contentWidth) {
+			b.pdf.SetX(b.area.x)
+			b.renderElement(child)
+		}
+	})
+}
+
+func (b *PDFBuilder) measureBlocks(element Element, width float64) float64 {
+	contentWidth, _ := b.getContentArea(width, element.Style)
+
+	height := 0.0
+	for _, child := range blockElements(element, contentWidth) {
+		height += b.measureElement(child, contentWidth)
+	}
+	return height
+}
+
+// layoutBlocks met en page les éléments convertis comme une suite d'éléments du flux
+func (b *PDFBuilder) layoutBlocks(s *layoutState, element Element, x, width float64) {
+	contentWidth, leftOffset := b.getContentArea(width, element.Style)
+	b.withArea(x+leftOffset, contentWidth, func() {
+		b.layoutElements(s, blockElements(element, contentWidth))
+	})
+}
@@ -0,0 +1,235 @@
+package template
+
+import "strings"
+
+// --- Cadres (box) : fond, bordures, padding et coins arrondis ---
+
+// boxFrame décrit le cadre d'un élément box dans une largeur donnée
+type boxFrame struct {
+	offset  float64 // décalage du cadre depuis le bord gauche de la zone (marge gauche)
+	width   float64 // largeur du cadre, marges déduites
+	padding Spacing
+}
+
+func newBoxFrame(style *Style, width float64) boxFrame {
+	frame := boxFrame{width: width}
+	if style == nil {
+		return frame
+	}
+
+	if len(style.Margin) > 0 {
+		margin := parseSpacing(style.Margin)
+		frame.offset = margin.Left
+		frame.width -= margin.Left + margin.Right
+	}
+	if len(style.Padding) > 0 {
+		frame.padding = parseSpacing(style.Padding)
+	}
+	return frame
+}
+
+// inner retourne la zone des enfants : décalage depuis le bord gauche de la zone et largeur
+func (f boxFrame) inner() (offset, width float64) {
+	return f.offset + f.padding.Left, f.width - f.padding.Left - f.padding.Right
+}
+
+// fixedHeight retourne la hauteur imposée d'un cadre (style.height), ou 0 si elle suit le contenu
+func fixedHeight(style *Style) float64 {
+	if style != nil && style.Height > 0 {
+		return style.Height
+	}
+	return 0
+}
+
+// setAreaHeight borne la hauteur de la zone courante à l'intérieur d'un cadre de hauteur imposée
+// (ajustement des textes avec fit) ; sans hauteur imposée, la zone garde celle du parent.
+// Un intérieur vide garde une hauteur minimale, 0 signifiant une zone non bornée.
+func (b *PDFBuilder) setAreaHeight(style *Style, frame boxFrame) {
+	if fixed := fixedHeight(style); fixed > 0 {
+		b.area.height = max(fixed-frame.padding.Top-frame.padding.Bottom, 0.001)
+	}
+}
+
+// renderBox dessine un cadre et ses enfants à la position courante (cadre enfant de grille).
+// Dans le flux principal, les cadres sont placés par layoutBox et peuvent être découpés entre pages.
+func (b *PDFBuilder) renderBox(element Element) {
+	frame := newBoxFrame(element.Style, b.area.width)
+	areaX := b.area.x
+	y := b.pdf.GetY()
+	height := b.measureBox(element, b.area.width)
+
+	// Le fond est dessiné avant les enfants
+	b.drawFrame(element.Style, areaX+frame.offset, y, frame.width, height, "1234")
+
+	innerOffset, innerWidth := frame.inner()
+	b.pdf.SetY(y + frame.padding.Top)
+	b.withArea(areaX+innerOffset, innerWidth, func() {
+		b.setAreaHeight(element.Style, frame)
+		for _, child := range element.Children {
+			b.pdf.SetX(b.area.x)
+			b.renderElement(child)
+		}
+	})
+
+	b.pdf.SetXY(areaX, y+height)
+}
+
+func (b *PDFBuilder) measureBox(element Element, width float64) float64 {
+	if height := fixedHeight(element.Style); height > 0 {
+		return height
+	}
+
+	frame := newBoxFrame(element.Style, width)
+	_, innerWidth := frame.inner()
+
+	height := frame.padding.Top + frame.padding.Bottom
+	for _, child := range element.Children {
+		height += b.measureElement(child, innerWidth)
+	}
+	return height
+}
+
+// layoutBox place un cadre et ses enfants. Les enfants sont mis en page dans la zone intérieure
+// comme des éléments du flux (sauts de page compris) ; le cadre est ensuite découpé en un
+// fragment par page occupée, inséré avant les enfants de cette page pour être dessiné dessous.
+func (b *PDFBuilder) layoutBox(s *layoutState, element Element, x, width float64) {
+	frame := newBoxFrame(element.Style, width)
+	innerOffset, innerWidth := frame.inner()
+	fixed := fixedHeight(element.Style)
+
+	// Le haut du cadre ne reste pas seul en bas de page : il faut au moins le début du premier enfant
+	needed := fixed
+	if fixed == 0 {
+		needed = frame.padding.Top + b.keepWithNextHeight(element.Children, innerWidth)
+	}
+	if !s.fits(needed) && !s.atPageTop() {
+		s.newPage()
+	}
+
+	start := len(s.boxes)
+	startPage, startY := s.page, s.y
+
+	s.y += frame.padding.Top
+	b.withArea(x+innerOffset, innerWidth, func() {
+		b.setAreaHeight(element.Style, frame)
+		b.layoutElements(s, element.Children)
+	})
+	s.y += frame.padding.Bottom
+
+	// Hauteur imposée : le contenu qui dépasse n'agrandit pas le cadre (sauf s'il change de page)
+	if fixed > 0 && s.page == startPage {
+		s.y = startY + fixed
+	}
+
+	children := append([]Box(nil), s.boxes[start:]...)
+	s.boxes = s.boxes[:start]
+
+	next := 0
+	for page := startPage; page <= s.page; page++ {
+		top, bottom := s.top, s.bottom
+		if page == startPage {
+			top = startY
+		}
+		if page == s.page {
+			bottom = s.y
+		}
+
+		s.boxes = append(s.boxes, Box{
+			Element: element, Page: page, X: x + frame.offset, Y: top, Width: frame.width, Height: bottom - top,
+			From: page - startPage, To: s.page - startPage + 1,
+		})
+		for next < len(children) && children[next].Page == page {
+			s.boxes = append(s.boxes, children[next])
+			next++
+		}
+	}
+}
+
+// paintBoxFrame dessine un fragment de cadre : seuls les coins du haut du premier fragment
+// et ceux du bas du dernier sont arrondis
+func (b *PDFBuilder) paintBoxFrame(element Element, box Box) {
+	corners := ""
+	if box.From == 0 {
+		corners += "12"
+	}
+	if box.From == box.To-1 {
+		corners += "34"
+	}
+	b.drawFrame(element.Style, box.X, box.Y, box.Width, box.Height, corners)
+}
+
+// drawFrame dessine le fond (bgColor) et les bordures d'un cadre. Border accepte "1" ou une
+// combinaison de "L", "T", "R", "B" ; borderColor ou borderWidth seuls impliquent "1".
+// Les coins (même codes que RoundedRect : "1" haut gauche à "4" bas gauche) ne sont arrondis
+// que si les quatre côtés sont tracés ou si le cadre n'a pas de bordure.
+func (b *PDFBuilder) drawFrame(style *Style, x, y, width, height float64, corners string) {
+	if style == nil || height <= 0 {
+		return
+	}
+
+	border := style.Border
+	if border == "" && (style.BorderColor != "" || style.BorderWidth > 0) {
+		border = "1"
+	}
+	if border == "0" {
+		border = ""
+	}
+	fill := style.BgColor != ""
+	if !fill && border == "" {
+		return
+	}
+
+	left := border == "1" || strings.Contains(border, "L")
+	top := border == "1" || strings.Contains(border, "T")
+	right := border == "1" || strings.Contains(border, "R")
+	bottom := border == "1" || strings.Contains(border, "B")
+	allSides := left && top && right && bottom
+
+	if fill {
+		r, g, blue := hexToRGB(style.BgColor)
+		b.pdf.SetFillColor(r, g, blue)
+	}
+	if style.BorderColor != "" {
+		r, g, blue := hexToRGB(style.BorderColor)
+		b.pdf.SetDrawColor(r, g, blue)
+	}
+	lineWidth := b.pdf.GetLineWidth()
+	if style.BorderWidth > 0 {
+		b.pdf.SetLineWidth(style.BorderWidth)
+	}
+
+	if style.Radius > 0 && (allSides || border == "") {
+		op := ""
+		if fill {
+			op += "F"
+		}
+		if allSides {
+			op += "D"
+		}
+		b.pdf.RoundedRect(x, y, width, height, style.Radius, corners, op)
+	} else {
+		if fill {
+			b.pdf.Rect(x, y, width, height, "F")
+		}
+		if allSides {
+			b.pdf.Rect(x, y, width, height, "D")
+		} else {
+			if left {
+				b.pdf.Line(x, y, x, y+height)
+			}
+			if top {
+				b.pdf.Line(x, y, x+width, y)
+			}
+			if right {
+				b.pdf.Line(x+width, y, x+width, y+height)
+			}
+			if bottom {
+				b.pdf.Line(x, y+height, x+width, y+height)
+			}
+		}
+	}
+
+	// Remettre l'épaisseur et la couleur par défaut
+	b.pdf.SetLineWidth(lineWidth)
+	b.pdf.SetDrawColor(0, 0, 0)
+}
@@ -0,0 +1,96 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// pageStreams retourne le flux de contenu non compressé de chacune des premières pages d'un document
+func pageStreams(t *testing.T, pdf []byte, pages int) []string {
+	t.Helper()
+	var contents []string
+	for _, stream := range regexp.MustCompile(`(?s)stream\n(.*?)endstream`).FindAllSubmatch(pdf, pages) {
+		contents = append(contents, string(stream[1]))
+	}
+	if len(contents) != pages {
+		t.Fatalf("%d flux de page, attendu %d", len(contents), pages)
+	}
+	return contents
+}
+
+// frameOps compte les opérations de dessin d'un cadre : segments, rectangles tracés et remplis, arcs
+func frameOps(content string) string {
+	return fmt.Sprintf("lignes %d, tracés %d, remplis %d, arcs %d",
+		strings.Count(content, " l S\n"), strings.Count(content, " re S\n"),
+		strings.Count(content, " re f\n"), strings.Count(content, " c \n"))
+}
+
+func TestDrawFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		style   *Style
+		corners string
+		want    string
+	}{
+		{"sans style", nil, "1234", "lignes 0, tracés 0, remplis 0, arcs 0"},
+		{"bordure complète", &Style{Border: "1"}, "1234", "lignes 0, tracés 1, remplis 0, arcs 0"},
+		{"quatre côtés", &Style{Border: "LTRB"}, "1234", "lignes 0, tracés 1, remplis 0, arcs 0"},
+		{"gauche et haut", &Style{Border: "LT"}, "1234", "lignes 2, tracés 0, remplis 0, arcs 0"},
+		{"bas seul avec fond", &Style{Border: "B", BgColor: "#EEEEEE"}, "1234", "lignes 1, tracés 0, remplis 1, arcs 0"},
+		{"couleur seule", &Style{BorderColor: "#FF0000"}, "1234", "lignes 0, tracés 1, remplis 0, arcs 0"},
+		{"épaisseur seule", &Style{BorderWidth: 0.5}, "1234", "lignes 0, tracés 1, remplis 0, arcs 0"},
+		{"bordure 0 sans fond", &Style{Border: "0", BorderColor: "#FF0000"}, "1234", "lignes 0, tracés 0, remplis 0, arcs 0"},
+		{"fond seul", &Style{BgColor: "#EEEEEE"}, "1234", "lignes 0, tracés 0, remplis 1, arcs 0"},
+		{"arrondi", &Style{Border: "1", Radius: 3}, "1234", "lignes 0, tracés 0, remplis 0, arcs 4"},
+		{"arrondi du premier fragment", &Style{Border: "1", Radius: 3}, "12", "lignes 0, tracés 0, remplis 0, arcs 2"},
+		{"arrondi du dernier fragment", &Style{Border: "1", Radius: 3}, "34", "lignes 0, tracés 0, remplis 0, arcs 2"},
+		{"fragment du milieu", &Style{Border: "1", Radius: 3}, "", "lignes 0, tracés 0, remplis 0, arcs 0"},
+		{"arrondi du fond sans bordure", &Style{BgColor: "#EEEEEE", Radius: 3}, "1234", "lignes 0, tracés 0, remplis 0, arcs 4"},
+		{"côtés partiels non arrondis", &Style{Border: "LR", Radius: 3}, "1234", "lignes 2, tracés 0, remplis 0, arcs 0"},
+	}
+	for _, test := range tests {
+		builder := NewPDFBuilder(Template{})
+		builder.pdf.SetCompression(false)
+		builder.pdf.AddPage()
+		builder.drawFrame(test.style, 20, 20, 100, 40, test.corners)
+		var pdf bytes.Buffer
+		if err := builder.pdf.Output(&pdf); err != nil {
+			t.Fatal(err)
+		}
+		if got := frameOps(pageStreams(t, pdf.Bytes(), 1)[0]); got != test.want {
+			t.Errorf("%s : %s, attendu %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBoxFrameAcrossPages(t *testing.T) {
+	// Cadre arrondi sur trois pages : coins du haut sur la première, coins du bas sur la dernière
+	template := Template{Elements: []Element{{
+		Type:     "box",
+		Style:    &Style{Border: "1", Radius: 3},
+		Children: []Element{{Type: "text", Content: strings.Repeat("ligne\n", 70)}},
+	}}}
+	builder := NewPDFBuilder(template)
+	builder.pdf.SetCompression(false)
+	pdf, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"lignes 0, tracés 0, remplis 0, arcs 2",
+		"lignes 0, tracés 0, remplis 0, arcs 0",
+		"lignes 0, tracés 0, remplis 0, arcs 2",
+	}
+	if pages := builder.pdf.PageNo(); pages != len(want) {
+		t.Fatalf("cadre sur %d page(s), attendu %d", pages, len(want))
+	}
+	for page, content := range pageStreams(t, pdf, len(want)) {
+		if got := frameOps(content); got != want[page] {
+			t.Errorf("page %d : %s, attendu %s", page+1, got, want[page])
+		}
+	}
+}
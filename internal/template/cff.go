@@ -0,0 +1,149 @@
+package template
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// --- Conversion des contours CFF (OpenType .otf) en contours TrueType ---
+//
+// Les charstrings CFF sont interprétées par golang.org/x/image/font/sfnt, puis chaque
+// courbe cubique est approchée par des courbes quadratiques (découpage jusqu'à un écart
+// de cffTolerance unité de dessin) pour construire les tables glyf et loca. Les
+// instructions de hinting CFF sont perdues ; les métriques (hmtx, hhea, OS/2) et la table
+// cmap restent celles de la police.
+
+// cffTolerance est l'écart maximal accepté entre une cubique et ses quadratiques, en unités de dessin
+const cffTolerance = 0.5
+
+// convertCFF retourne une police TrueType équivalente à une police OpenType à contours CFF
+func convertCFF(data []byte) ([]byte, error) {
+	_, tables, err := readSFNT(data)
+	if err != nil {
+		return nil, err
+	}
+	if findTable(tables, "CFF2") != nil {
+		return nil, fmt.Errorf("les polices variables (CFF2) ne sont pas gérées")
+	}
+
+	font, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	ppem := fixed.I(int(font.UnitsPerEm()))
+
+	var buffer sfnt.Buffer
+	glyphs := make([][]byte, font.NumGlyphs())
+	maxPoints, maxContours := 0, 0
+	for i := range glyphs {
+		segments, err := font.LoadGlyph(&buffer, sfnt.GlyphIndex(i), ppem, nil)
+		if err != nil {
+			return nil, fmt.Errorf("glyphe %d : %w", i, err)
+		}
+		contours := cffContours(segments)
+		glyphs[i] = encodeSimpleGlyph(contours, nil, nil, false)
+
+		points := 0
+		for _, contour := range contours {
+			points += len(contour)
+		}
+		maxPoints, maxContours = max(maxPoints, points), max(maxContours, len(contours))
+	}
+
+	head, err := withLongLoca(findTable(tables, "head"))
+	if err != nil {
+		return nil, err
+	}
+
+	// maxp version 1.0 (TrueType) : limites des contours, pas d'instructions
+	maxp := make([]byte, 32)
+	binary.BigEndian.PutUint32(maxp, 0x00010000)
+	binary.BigEndian.PutUint16(maxp[4:], uint16(len(glyphs)))
+	binary.BigEndian.PutUint16(maxp[6:], uint16(maxPoints))
+	binary.BigEndian.PutUint16(maxp[8:], uint16(maxContours))
+	binary.BigEndian.PutUint16(maxp[14:], 2) // maxZones
+
+	glyf, loca := glyfTables(glyphs)
+	converted := []sfntTable{{"glyf", glyf}, {"loca", loca}, {"head", head}, {"maxp", maxp}}
+	for _, table := range tables {
+		switch table.tag {
+		case "CFF ", "VORG", "glyf", "loca", "head", "maxp":
+		default:
+			converted = append(converted, table)
+		}
+	}
+	return writeSFNT(sfntTrueType, converted), nil
+}
+
+// cffContours convertit les segments d'un glyphe (axe Y vers le bas, unités 26.6) en contours
+// TrueType : points arrondis, quadratiques, sens horaire pour les contours extérieurs
+func cffContours(segments sfnt.Segments) [][]glyphPoint {
+	type point struct{ x, y float64 }
+	at := func(p fixed.Point26_6) point {
+		return point{float64(p.X) / 64, -float64(p.Y) / 64}
+	}
+	round := func(p point, onCurve bool) glyphPoint {
+		return glyphPoint{int(math.Round(p.x)), int(math.Round(p.y)), onCurve}
+	}
+
+	var contours [][]glyphPoint
+	var contour []glyphPoint
+	var current point
+	closeContour := func() {
+		// Le dernier point répète souvent le premier
+		if n := len(contour); n > 1 && contour[n-1] == contour[0] {
+			contour = contour[:n-1]
+		}
+		if len(contour) > 0 {
+			// CFF tourne dans le sens inverse de TrueType : même point de départ, ordre inversé
+			for i, j := 1, len(contour)-1; i < j; i, j = i+1, j-1 {
+				contour[i], contour[j] = contour[j], contour[i]
+			}
+			contours = append(contours, contour)
+		}
+		contour = nil
+	}
+
+	// quadratics approche une cubique par des quadratiques, en la coupant en deux tant que l'écart est trop grand
+	var quadratics func(p0, p1, p2, p3 point, depth int)
+	quadratics = func(p0, p1, p2, p3 point, depth int) {
+		dx := p3.x - 3*p2.x + 3*p1.x - p0.x
+		dy := p3.y - 3*p2.y + 3*p1.y - p0.y
+		if depth >= 8 || math.Sqrt(3)/36*math.Hypot(dx, dy) <= cffTolerance {
+			control := point{(3*(p1.x+p2.x) - p0.x - p3.x) / 4, (3*(p1.y+p2.y) - p0.y - p3.y) / 4}
+			contour = append(contour, round(control, false), round(p3, true))
+			return
+		}
+		mid := func(a, b point) point { return point{(a.x + b.x) / 2, (a.y + b.y) / 2} }
+		p01, p12, p23 := mid(p0, p1), mid(p1, p2), mid(p2, p3)
+		p012, p123 := mid(p01, p12), mid(p12, p23)
+		split := mid(p012, p123)
+		quadratics(p0, p01, p012, split, depth+1)
+		quadratics(split, p123, p23, p3, depth+1)
+	}
+
+	for _, segment := range segments {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			closeContour()
+			current = at(segment.Args[0])
+			contour = append(contour, round(current, true))
+		case sfnt.SegmentOpLineTo:
+			current = at(segment.Args[0])
+			contour = append(contour, round(current, true))
+		case sfnt.SegmentOpQuadTo:
+			current = at(segment.Args[1])
+			contour = append(contour, round(at(segment.Args[0]), false), round(current, true))
+		case sfnt.SegmentOpCubeTo:
+			end := at(segment.Args[2])
+			quadratics(current, at(segment.Args[0]), at(segment.Args[1]), end, 0)
+			current = end
+		}
+	}
+	closeContour()
+	return contours
+}
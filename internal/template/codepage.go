@@ -0,0 +1,127 @@
+package template
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// --- Encodage du texte selon la police ---
+//
+// Les polices UTF-8 (TrueType chargées par fonts) reçoivent le texte tel quel. Les
+// polices 8 bits reçoivent un octet par caractère, selon une page de codes : les polices
+// standard du PDF (Arial/Helvetica, Times, Courier) sont déclarées en WinAnsiEncoding,
+// donc toujours en cp1252 ; les polices 8 bits générées par makefont (fonts.definitions)
+// utilisent la page de codes de leur définition (cp1250 pour l'Europe centrale, cp1251
+// pour le cyrillique, iso-8859-15...). Les tables de correspondance sont celles de gofpdf.
+
+//go:embed codepage/*.map
+var codePageFiles embed.FS
+
+// defaultCodePage est la page de codes des polices standard (WinAnsiEncoding)
+const defaultCodePage = "cp1252"
+
+var (
+	translatorsMu sync.Mutex
+	translators   = map[string]func(string) string{}
+)
+
+// translatorFor retourne le traducteur UTF-8 -> page de codes ("cp1250", "iso-8859-15"...),
+// ou nil si la page de codes n'est pas embarquée ; les caractères absents deviennent "."
+func translatorFor(codePage string) func(string) string {
+	codePage = strings.ToLower(codePage)
+
+	translatorsMu.Lock()
+	defer translatorsMu.Unlock()
+	if tr, ok := translators[codePage]; ok {
+		return tr
+	}
+
+	data, err := codePageFiles.ReadFile("codepage/" + codePage + ".map")
+	if err != nil {
+		return nil
+	}
+	tr, err := gofpdf.UnicodeTranslator(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	translators[codePage] = tr
+	return tr
+}
+
+// encodeFor prépare un texte pour une famille : tel quel pour une police UTF-8, traduit dans
+// la page de codes de la police sinon
+func (b *PDFBuilder) encodeFor(family, text string) string {
+	key := strings.ToLower(family)
+	if b.utf8Fonts[key] {
+		return text
+	}
+	codePage := defaultCodePage
+	if cp, ok := b.codePages[key]; ok {
+		codePage = cp
+	}
+	if tr := translatorFor(codePage); tr != nil {
+		return tr(text)
+	}
+	return translatorFor(defaultCodePage)(text)
+}
+
+// encode prépare un texte pour la police courante
+func (b *PDFBuilder) encode(text string) string {
+	return b.encodeFor(b.font.family, text)
+}
+
+// addFontDefinition charge une police 8 bits générée par makefont (fichiers .json et .z en base64,
+// décodés via le registre des polices) et retient sa page de codes : celle déclarée, sinon celle de la définition (champ Enc)
+func (b *PDFBuilder) addFontDefinition(family string, definition FontDefinition) error {
+	variants := []struct {
+		style string
+		files FontDefinitionFiles
+	}{
+		{"", definition.Regular},
+		{"B", definition.Bold},
+		{"I", definition.Italic},
+		{"BI", definition.BoldItalic},
+	}
+
+	for _, variant := range variants {
+		if variant.files.JSON == "" {
+			continue
+		}
+		jsonFile, err := registerBase64Font(variant.files.JSON)
+		if err != nil {
+			return fmt.Errorf("définition de police %s : fichier .json : %w", family, err)
+		}
+		zFile, err := registerBase64Font(variant.files.Z)
+		if err != nil {
+			return fmt.Errorf("définition de police %s : fichier .z : %w", family, err)
+		}
+
+		var desc struct {
+			Name, Enc    string
+			OriginalSize int
+		}
+		if err := json.Unmarshal(jsonFile.data, &desc); err != nil {
+			return fmt.Errorf("définition de police %s : fichier .json : %w", family, err)
+		}
+		b.pdf.AddFontFromBytes(family, variant.style, jsonFile.data, zFile.data)
+		b.fontFiles[desc.Name] = fontFile{family: family, style: variant.style, size: desc.OriginalSize}
+
+		if _, ok := b.codePages[strings.ToLower(family)]; ok {
+			continue
+		}
+		codePage := definition.CodePage
+		if codePage == "" {
+			codePage = desc.Enc
+		}
+		if codePage != "" {
+			b.codePages[strings.ToLower(family)] = codePage
+		}
+	}
+	return nil
+}
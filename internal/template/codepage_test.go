@@ -0,0 +1,93 @@
+package template
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// fontDefinitionFiles génère avec makefont la police 8 bits DejaVu Sans dans une page de codes
+func fontDefinitionFiles(t *testing.T, codePage string) FontDefinitionFiles {
+	t.Helper()
+	dir := t.TempDir()
+	if err := gofpdf.MakeFont("fontpack/DejaVuSans.ttf", "codepage/"+codePage+".map", dir, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	files := FontDefinitionFiles{}
+	for _, file := range []struct {
+		name   string
+		target *string
+	}{{"DejaVuSans.json", &files.JSON}, {"DejaVuSans.z", &files.Z}} {
+		data, err := os.ReadFile(filepath.Join(dir, file.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		*file.target = base64.StdEncoding.EncodeToString(data)
+	}
+	return files
+}
+
+func TestTranslator(t *testing.T) {
+	tests := []struct {
+		codePage, text, want string
+	}{
+		{"cp1252", "Zürich 12 €", "Z\xfcrich 12 \x80"},
+		{"CP1252", "œ « »", "\x9c \xab \xbb"},
+		{"cp1250", "Přílišný kůň", "P\xf8\xedli\x9an\xfd k\xf9\xf2"},
+		{"cp1251", "Жёлтый", "\xc6\xb8\xeb\xf2\xfb\xe9"},
+		{"iso-8859-15", "€ Š", "\xa4 \xa6"},
+		{"iso-8859-1", "€", "."}, // caractère absent de la page de codes
+		{"koi8-r", "Ж", "\xf6"},
+	}
+	for _, test := range tests {
+		tr := translatorFor(test.codePage)
+		if tr == nil {
+			t.Fatalf("page de codes %s absente", test.codePage)
+		}
+		if got := tr(test.text); got != test.want {
+			t.Errorf("%s %q : %q, attendu %q", test.codePage, test.text, got, test.want)
+		}
+	}
+	if translatorFor("cp437") != nil {
+		t.Error("traducteur trouvé pour une page de codes non embarquée")
+	}
+}
+
+func TestEncodeFor(t *testing.T) {
+	cyrillic := fontDefinitionFiles(t, "cp1251")
+	template := Template{
+		Fonts: FontConfig{
+			Paths: map[string]string{"DejaVu": "fontpack/DejaVuSans.ttf"},
+			Definitions: map[string]FontDefinition{
+				"Cyrillique": {Regular: cyrillic},
+				"Déclarée":   {Regular: cyrillic, CodePage: "koi8-r"},
+				"Inconnue":   {Regular: cyrillic, CodePage: "cp437"},
+			},
+		},
+		Elements: []Element{{Type: "text", Content: "Texte"}},
+	}
+	builder := NewPDFBuilder(template)
+	builder.Layout()
+	if err := builder.pdf.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		family, text, want string
+	}{
+		{"DejaVu", "Жёлтый €", "Жёлтый €"},                   // UTF-8 : texte tel quel
+		{"dejavu", "€", "€"},                                 // famille sans distinction de casse
+		{"Arial", "Zürich €", "Z\xfcrich \x80"},              // police standard : cp1252
+		{"Cyrillique", "Жёлтый", "\xc6\xb8\xeb\xf2\xfb\xe9"}, // page de codes de la définition
+		{"Déclarée", "Ж", "\xf6"},                            // page de codes déclarée
+		{"Inconnue", "Zürich", "Z\xfcrich"},                  // page de codes absente : cp1252
+	}
+	for _, test := range tests {
+		if got := builder.encodeFor(test.family, test.text); got != test.want {
+			t.Errorf("%s %q : %q, attendu %q", test.family, test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// --- Tableaux liés aux données (dataSource) ---
+
+// isDataTable indique si un tableau est lié à une source de données. Seuls ces tableaux répètent
+// leurs en-têtes après un saut de page et remplissent les lignes qui ont un bgColor ; les lignes
+// d'un tableau statique se suivent d'une page à l'autre, sans remplissage.
+func isDataTable(element Element) bool {
+	return element.DataSource != ""
+}
+
+// dataRows génère les lignes d'un tableau depuis le tableau de variables désigné par DataSource
+func (b *PDFBuilder) dataRows(element Element) []TableRow {
+	source, _ := lookupValue(b.vars, element.DataSource)
+	items := dataItems(source)
+
+	if len(items) == 0 {
+		if element.EmptyText == "" {
+			return nil
+		}
+		return []TableRow{{Cells: []string{element.EmptyText}, kind: rowEmpty}}
+	}
+
+	if element.SortBy != "" {
+		sortItems(items, element.SortBy, element.SortOrder == "desc")
+	}
+
+	// Variables de position, comme pour les boucles {{#array}} ; un champ index ou index1 des
+	// données est conservé
+	for i, item := range items {
+		if _, ok := item["index"]; !ok {
+			item["index"] = i
+		}
+		if _, ok := item["index1"]; !ok {
+			item["index1"] = i + 1
+		}
+	}
+
+	var rows []TableRow
+	if element.GroupBy == "" {
+		rows = itemRows(element.Columns, items)
+	} else {
+		for _, group := range groupItems(items, element.GroupBy) {
+			if element.GroupHeader != nil {
+				rows = append(rows, TableRow{
+					Cells: []string{summaryLabel(element.GroupHeader.Label, group.key)},
+					Style: summaryStyle(element.GroupHeader),
+					kind:  rowGroupHeader,
+				})
+			}
+			rows = append(rows, itemRows(element.Columns, group.items)...)
+			if element.GroupFooter != nil {
+				rows = append(rows, summaryRow(element.GroupFooter, element.Columns, group.items, group.key, rowGroupFooter))
+			}
+		}
+	}
+
+	if element.GrandTotal != nil {
+		rows = append(rows, summaryRow(element.GrandTotal, element.Columns, items, "", rowGrandTotal))
+	}
+
+	return rows
+}
+
+// itemRows génère une ligne par item en appliquant les liaisons field/format des colonnes
+func itemRows(columns []TableColumn, items []map[string]interface{}) []TableRow {
+	rows := make([]TableRow, 0, len(items))
+	for _, item := range items {
+		row := TableRow{Cells: make([]string, len(columns)), values: make([]interface{}, len(columns))}
+		for c, col := range columns {
+			if col.Field == "" {
+				continue
+			}
+			value, _ := lookupValue(item, col.Field)
+			row.Cells[c] = formatValue(value, col.Format)
+			row.values[c] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+type itemGroup struct {
+	key   string
+	items []map[string]interface{}
+}
+
+// groupItems regroupe les items par valeur du champ, dans l'ordre de première apparition
+func groupItems(items []map[string]interface{}, field string) []itemGroup {
+	var groups []itemGroup
+	index := make(map[string]int)
+
+	for _, item := range items {
+		value, _ := lookupValue(item, field)
+		key := displayString(value)
+
+		i, exists := index[key]
+		if !exists {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, itemGroup{key: key})
+		}
+		groups[i].items = append(groups[i].items, item)
+	}
+
+	return groups
+}
+
+// summaryRow construit une ligne de sous-total ou de total : le libellé occupe la première
+// colonne sans agrégat, les agrégats s'affichent dans la colonne liée au même champ
+func summaryRow(spec *TableSummaryRow, columns []TableColumn, items []map[string]interface{}, key string, kind rowKind) TableRow {
+	row := TableRow{Cells: make([]string, len(columns)), Style: summaryStyle(spec), kind: kind}
+
+	labelPlaced := false
+	for c, col := range columns {
+		fn, hasAggregate := spec.Aggregates[col.Field]
+		if col.Field == "" || !hasAggregate {
+			if !labelPlaced {
+				row.Cells[c] = summaryLabel(spec.Label, key)
+				labelPlaced = true
+			}
+			continue
+		}
+
+		if value, ok := aggregate(items, col.Field, fn); ok {
+			if fn == "count" {
+				row.Cells[c] = formatValue(int(value), "")
+			} else {
+				row.Cells[c] = formatValue(value, col.Format)
+			}
+		}
+	}
+
+	return row
+}
+
+// carryForwardColumn retourne l'index de la colonne cumulée (par champ lié, sinon par en-tête), ou -1
+func carryForwardColumn(columns []TableColumn, field string) int {
+	for c, col := range columns {
+		if col.Field != "" && col.Field == field {
+			return c
+		}
+	}
+	for c, col := range columns {
+		if col.Header == field {
+			return c
+		}
+	}
+	return -1
+}
+
+// runningTotal cumule la colonne suivie sur les lignes de données ; false si aucune ligne de données
+func runningTotal(rows []TableRow, column int) (float64, bool) {
+	total := 0.0
+	found := false
+	for _, row := range rows {
+		if row.kind != rowData {
+			continue
+		}
+		found = true
+		if n, ok := toNumber(row.value(column)); ok {
+			total += n
+		}
+	}
+	return total, found
+}
+
+// carryForwardRow construit une ligne de report : libellé dans la première autre colonne, cumul dans la colonne suivie
+func carryForwardRow(spec *TableCarryForward, columns []TableColumn, column int, total float64, label, defaultLabel string) TableRow {
+	if label == "" {
+		label = defaultLabel
+	}
+
+	style := spec.Style
+	if style == nil {
+		style = &Style{Bold: true, Italic: true}
+	}
+
+	row := TableRow{Cells: make([]string, len(columns)), Style: style, kind: rowCarryForward}
+	for c := range columns {
+		if c != column {
+			row.Cells[c] = label
+			break
+		}
+	}
+	row.Cells[column] = formatValue(total, columns[column].Format)
+
+	return row
+}
+
+// summaryLabel insère la valeur du groupe dans le libellé si celui-ci contient un verbe printf
+// de chaîne (%s, %v, %q) ; un « % » isolé (« Taux 100% ») et les verbes suivants sont laissés tels quels
+func summaryLabel(label, key string) string {
+	switch formatVerb(label) {
+	case 's', 'v', 'q':
+		return formatOnce(label, key)
+	}
+	return label
+}
+
+// summaryStyle retourne le style d'une ligne de synthèse (gras par défaut)
+func summaryStyle(spec *TableSummaryRow) *Style {
+	if spec.Style != nil {
+		return spec.Style
+	}
+	return &Style{Bold: true}
+}
+
+// aggregate calcule une fonction d'agrégat sur les valeurs numériques d'un champ
+func aggregate(items []map[string]interface{}, field, fn string) (float64, bool) {
+	if fn == "count" {
+		return float64(len(items)), true
+	}
+
+	var result float64
+	count := 0
+	for _, item := range items {
+		value, _ := lookupValue(item, field)
+		n, ok := toNumber(value)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case count == 0:
+			result = n
+		case fn == "min" && n < result:
+			result = n
+		case fn == "max" && n > result:
+			result = n
+		case fn == "sum" || fn == "avg":
+			result += n
+		}
+		count++
+	}
+
+	if count == 0 {
+		return 0, false
+	}
+
+	switch fn {
+	case "sum", "min", "max":
+		return result, true
+	case "avg":
+		return result / float64(count), true
+	}
+	return 0, false
+}
+
+// dataItems normalise une source de données en liste d'objets (copiés, pour ne pas modifier pdfVars)
+func dataItems(source interface{}) []map[string]interface{} {
+	var items []map[string]interface{}
+
+	appendItem := func(value interface{}) {
+		item := make(map[string]interface{})
+		if m, ok := value.(map[string]interface{}); ok {
+			for k, v := range m {
+				item[k] = v
+			}
+		} else {
+			// Si l'item n'est pas un objet, l'assigner à "item"
+			item["item"] = value
+		}
+		items = append(items, item)
+	}
+
+	switch arr := source.(type) {
+	case []interface{}:
+		for _, value := range arr {
+			appendItem(value)
+		}
+	case []map[string]interface{}:
+		for _, value := range arr {
+			appendItem(value)
+		}
+	}
+
+	return items
+}
+
+// sortItems trie les items selon un champ (numérique si possible, sinon alphabétique)
+func sortItems(items []map[string]interface{}, field string, desc bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, _ := lookupValue(items[i], field)
+		c, _ := lookupValue(items[j], field)
+		if desc {
+			return compareValues(c, a) < 0
+		}
+		return compareValues(a, c) < 0
+	})
+}
+
+// compareValues compare deux valeurs : numériquement si les deux sont des nombres, sinon comme chaînes
+func compareValues(a, b interface{}) int {
+	na, okA := toNumber(a)
+	nb, okB := toNumber(b)
+	if okA && okB {
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(displayString(a), displayString(b))
+}
+
+// toNumber convertit une valeur (nombre JSON ou chaîne numérique comme "2800.00") en float64
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// formatValue met en forme une valeur de cellule avec un format printf optionnel.
+// Les verbes numériques (%d, %f, %e, %g...) convertissent d'abord les chaînes numériques ;
+// une valeur non numérique (« n/a ») est alors affichée telle quelle.
+func formatValue(value interface{}, format string) string {
+	if format == "" || !strings.Contains(format, "%") {
+		return displayString(value)
+	}
+
+	verb := formatVerb(format)
+	switch verb {
+	case 'd', 'x', 'X', 'o', 'b':
+		if n, ok := toNumber(value); ok {
+			return formatOnce(format, int64(n))
+		}
+		return displayString(value)
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		if n, ok := toNumber(value); ok {
+			return formatOnce(format, n)
+		}
+		return displayString(value)
+	}
+	return formatOnce(format, displayString(value))
+}
+
+// formatOnce applique un format printf à une seule valeur : le premier verbe reçoit la valeur,
+// « %% » donne « % », les autres « % » (verbes suivants, « % » isolé) restent tels quels
+func formatOnce(format string, value interface{}) string {
+	var out strings.Builder
+	used := false
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			out.WriteByte('%')
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(format) && strings.IndexByte("+-# 0123456789.", format[end]) >= 0 {
+			end++
+		}
+		if used || end == len(format) {
+			out.WriteByte('%')
+			continue
+		}
+		out.WriteString(fmt.Sprintf(format[i:end+1], value))
+		used = true
+		i = end
+	}
+	return out.String()
+}
+
+// formatVerb retourne le premier verbe printf d'un format (en ignorant "%%")
+func formatVerb(format string) byte {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		if i < len(format) && format[i] == '%' {
+			continue
+		}
+		for i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+			i++
+		}
+		if i < len(format) {
+			return format[i]
+		}
+	}
+	return 0
+}
+
+// displayString convertit une valeur en texte affichable (sans échappement JSON, contrairement à valueToString)
+func displayString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case int, int64, int32:
+		return fmt.Sprintf("%d", v)
+	case float64, float32:
+		return fmt.Sprintf("%.2f", v)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,405 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSummaryLabel(t *testing.T) {
+	tests := []struct {
+		label, key, want string
+	}{
+		{"Sous-total", "A", "Sous-total"},
+		{"Compte %s", "512", "Compte 512"},
+		{"Compte %v (%q)", "512", "Compte 512 (%q)"}, // seul le premier verbe reçoit la valeur
+		{"Taux 100%", "A", "Taux 100%"},
+		{"Taux 100% TVA", "A", "Taux 100% TVA"},
+		{"100%% de %s", "A", "100% de A"},
+	}
+	for _, tt := range tests {
+		if got := summaryLabel(tt.label, tt.key); got != tt.want {
+			t.Errorf("summaryLabel(%q, %q) = %q, attendu %q", tt.label, tt.key, got, tt.want)
+		}
+	}
+}
+
+// tableTemplate retourne un tableau de n lignes, statique ou lié aux données
+func tableTemplate(n int, data bool) (Template, map[string]interface{}) {
+	element := Element{
+		Type:    "table",
+		Columns: []TableColumn{{Header: "Libellé", Width: 100, Field: "label"}},
+	}
+	var items []interface{}
+	var rows []TableRow
+	for i := 0; i < n; i++ {
+		label := fmt.Sprintf("Ligne %d", i)
+		items = append(items, map[string]interface{}{"label": label})
+		rows = append(rows, TableRow{Cells: []string{label}, Style: &Style{BgColor: "#EEEEEE"}})
+	}
+	if data {
+		element.DataSource = "items"
+		element.GrandTotal = &TableSummaryRow{Label: "Total", Style: &Style{BgColor: "#EEEEEE"}}
+	} else {
+		element.Rows = rows
+	}
+	return Template{Elements: []Element{element}}, map[string]interface{}{"items": items}
+}
+
+func TestTableHeaderRepeat(t *testing.T) {
+	for _, data := range []bool{false, true} {
+		template, vars := tableTemplate(60, data)
+		builder := NewPDFBuilder(template)
+		builder.vars = vars
+		boxes := builder.Layout()
+		if len(boxes) < 2 {
+			t.Fatalf("data=%v : %d fragment(s), attendu au moins 2", data, len(boxes))
+		}
+
+		next := boxes[1]
+		height := float64(next.To-next.From) * tableRowHeight
+		if data {
+			height += tableRowHeight // en-têtes répétés
+		}
+		if next.Height != height {
+			t.Errorf("data=%v : fragment de %v mm, attendu %v mm", data, next.Height, height)
+		}
+	}
+}
+
+func TestTableRowFill(t *testing.T) {
+	for _, data := range []bool{false, true} {
+		template, vars := tableTemplate(3, data)
+		builder := NewPDFBuilder(template)
+		builder.vars = vars
+		builder.pdf.SetCompression(false)
+		pdf, err := builder.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Cellule remplie et bordée : rectangle « re B » ; bordée seulement : « re S »
+		filled := bytes.Contains(pdf, []byte(" re B"))
+		if filled != data {
+			t.Errorf("data=%v : lignes remplies = %v", data, filled)
+		}
+	}
+}
+
+// salesItems sont les lignes des tests de regroupement : région, produit, montant (nombre ou chaîne)
+func salesItems() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"region": "Nord", "product": "B", "amount": 120.0},
+		{"region": "Sud", "product": "A", "amount": "80.50"},
+		{"region": "Nord", "product": "C", "amount": 30.0},
+		{"region": "Est", "product": "A", "amount": "n/a"},
+		{"region": "Sud", "product": "D", "amount": 200},
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		fn   string
+		want float64
+		ok   bool
+	}{
+		{"sum", 430.5, true},
+		{"avg", 430.5 / 4, true}, // « n/a » ignoré
+		{"min", 30, true},
+		{"max", 200, true},
+		{"count", 5, true}, // toutes les lignes, numériques ou non
+		{"median", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := aggregate(salesItems(), "amount", test.fn)
+		if got != test.want || ok != test.ok {
+			t.Errorf("%s : (%v, %v), attendu (%v, %v)", test.fn, got, ok, test.want, test.ok)
+		}
+	}
+	if _, ok := aggregate(salesItems(), "product", "sum"); ok {
+		t.Error("somme d'un champ sans valeur numérique")
+	}
+}
+
+func TestGroupItems(t *testing.T) {
+	groups := groupItems(salesItems(), "region")
+	want := []struct {
+		key      string
+		products string
+	}{{"Nord", "BC"}, {"Sud", "AD"}, {"Est", "A"}} // ordre de première apparition
+	if len(groups) != len(want) {
+		t.Fatalf("%d groupe(s), attendu %d", len(groups), len(want))
+	}
+	for i, group := range groups {
+		products := ""
+		for _, item := range group.items {
+			products += item["product"].(string)
+		}
+		if group.key != want[i].key || products != want[i].products {
+			t.Errorf("groupe %d : %s %s, attendu %s %s", i, group.key, products, want[i].key, want[i].products)
+		}
+	}
+}
+
+func TestSortItems(t *testing.T) {
+	tests := []struct {
+		field string
+		desc  bool
+		want  string
+	}{
+		{"amount", false, "CABDA"}, // numérique ; « n/a » comparé comme chaîne, après les nombres
+		{"amount", true, "ADBAC"},
+		{"region", false, "ABCAD"}, // alphabétique, tri stable
+		{"product", true, "DCBAA"},
+	}
+	for _, test := range tests {
+		items := salesItems()
+		sortItems(items, test.field, test.desc)
+		got := ""
+		for _, item := range items {
+			got += item["product"].(string)
+		}
+		if got != test.want {
+			t.Errorf("tri par %s (desc=%v) : %s, attendu %s", test.field, test.desc, got, test.want)
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		format string
+		want   string
+	}{
+		{1250.5, "", "1250.50"},
+		{"2800.00", "%.1f €", "2800.0 €"},
+		{"12", "%03d", "012"},
+		{42.9, "%d", "42"},
+		{"n/a", "%.2f €", "n/a"}, // non numérique : tel quel
+		{"texte", "[%s]", "[texte]"},
+		{7, "100%%", "100%"},         // sans verbe : texte fixe
+		{"12", "%d (%s)", "12 (%s)"}, // verbes suivants laissés tels quels
+		{"a", "%s / %s", "a / %s"},
+		{12.5, "%.1f %", "12.5 %"},
+		{nil, "", ""},
+		{true, "", "true"},
+	}
+	for _, test := range tests {
+		if got := formatValue(test.value, test.format); got != test.want {
+			t.Errorf("formatValue(%#v, %q) = %q, attendu %q", test.value, test.format, got, test.want)
+		}
+	}
+}
+
+func TestDataRows(t *testing.T) {
+	element := Element{
+		Type:       "table",
+		DataSource: "sales",
+		SortBy:     "product",
+		Columns: []TableColumn{
+			{Header: "Produit", Field: "product"},
+			{Header: "Montant", Field: "amount", Format: "%.2f"},
+		},
+		GroupBy:     "region",
+		GroupHeader: &TableSummaryRow{Label: "Région %s"},
+		GroupFooter: &TableSummaryRow{Label: "Total %s", Aggregates: map[string]string{"amount": "sum"}},
+		GrandTotal:  &TableSummaryRow{Label: "Total", Aggregates: map[string]string{"amount": "count"}},
+	}
+	var sales []interface{}
+	for _, item := range salesItems() {
+		sales = append(sales, item)
+	}
+
+	tests := []struct {
+		name    string
+		element func(Element) Element
+		want    []string
+	}{
+		{"groupes triés", func(e Element) Element { return e }, []string{
+			"Région Sud",
+			"A|80.50", "D|200.00", "Total Sud|280.50",
+			"Région Est",
+			"A|n/a", "Total Est|",
+			"Région Nord",
+			"B|120.00", "C|30.00", "Total Nord|150.00",
+			"Total|5",
+		}},
+		{"sans regroupement", func(e Element) Element {
+			e.GroupBy, e.GrandTotal = "", nil
+			e.SortOrder = "desc"
+			return e
+		}, []string{"D|200.00", "C|30.00", "B|120.00", "A|80.50", "A|n/a"}},
+		{"source vide", func(e Element) Element {
+			e.DataSource, e.EmptyText = "absent", "Aucune vente"
+			return e
+		}, []string{"Aucune vente"}},
+	}
+	for _, test := range tests {
+		builder := NewPDFBuilder(Template{})
+		builder.vars = map[string]interface{}{"sales": sales}
+		var got []string
+		for _, row := range builder.dataRows(test.element(element)) {
+			got = append(got, strings.Join(row.Cells, "|"))
+		}
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("%s :\n%s\nattendu :\n%s", test.name, strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+		}
+	}
+}
+
+func TestDataRowsIndex(t *testing.T) {
+	element := Element{
+		Type:       "table",
+		DataSource: "items",
+		SortBy:     "name",
+		Columns:    []TableColumn{{Field: "name"}, {Field: "index"}, {Field: "index1"}},
+	}
+	tests := []struct {
+		name  string
+		items []interface{}
+		want  []string
+	}{
+		{"position après tri", []interface{}{
+			map[string]interface{}{"name": "b"},
+			map[string]interface{}{"name": "a"},
+		}, []string{"a|0|1", "b|1|2"}},
+		{"champs des données conservés", []interface{}{
+			map[string]interface{}{"name": "b", "index": "B-7"},
+			map[string]interface{}{"name": "a", "index": "A-3", "index1": 10},
+		}, []string{"a|A-3|10", "b|B-7|2"}},
+	}
+	for _, test := range tests {
+		builder := NewPDFBuilder(Template{})
+		builder.vars = map[string]interface{}{"items": test.items}
+		var got []string
+		for _, row := range builder.dataRows(element) {
+			got = append(got, strings.Join(row.Cells, "|"))
+		}
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("%s : %v, attendu %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCarryForwardColumn(t *testing.T) {
+	columns := []TableColumn{{Header: "Libellé", Field: "label"}, {Header: "Montant", Field: "amount"}, {Header: "Solde"}}
+	tests := []struct {
+		field string
+		want  int
+	}{
+		{"amount", 1}, // champ lié
+		{"Solde", 2},  // en-tête d'une colonne sans champ
+		{"Libellé", 0},
+		{"total", -1},
+	}
+	for _, test := range tests {
+		if got := carryForwardColumn(columns, test.field); got != test.want {
+			t.Errorf("colonne de %q : %d, attendu %d", test.field, got, test.want)
+		}
+	}
+}
+
+func TestRunningTotal(t *testing.T) {
+	data := func(value interface{}) TableRow { return TableRow{values: []interface{}{"x", value}} }
+	tests := []struct {
+		name  string
+		rows  []TableRow
+		total float64
+		ok    bool
+	}{
+		{"nombres et chaînes numériques", []TableRow{data(10.5), data("4.50"), data(5)}, 20, true},
+		{"valeurs non numériques ignorées", []TableRow{data(10.0), data("n/a"), data(nil)}, 10, true},
+		{"sous-totaux ignorés", []TableRow{data(10.0), {kind: rowGroupFooter, Cells: []string{"", "10"}}, data(1.0)}, 11, true},
+		{"sans ligne de données", []TableRow{{kind: rowGroupHeader, Cells: []string{"Groupe"}}}, 0, false},
+	}
+	for _, test := range tests {
+		total, ok := runningTotal(test.rows, 1)
+		if total != test.total || ok != test.ok {
+			t.Errorf("%s : (%v, %v), attendu (%v, %v)", test.name, total, ok, test.total, test.ok)
+		}
+	}
+}
+
+func TestCarryForwardRow(t *testing.T) {
+	columns := []TableColumn{{Header: "Montant", Field: "amount", Format: "%.2f €"}, {Header: "Libellé"}, {Header: "Note"}}
+	custom := &Style{Bold: true}
+	tests := []struct {
+		name  string
+		spec  *TableCarryForward
+		label string
+		want  string
+		style *Style
+	}{
+		{"libellé par défaut", &TableCarryForward{}, "", "1250.50 €|À reporter|", nil},
+		{"libellé déclaré", &TableCarryForward{Style: custom}, "Übertrag", "1250.50 €|Übertrag|", custom},
+	}
+	for _, test := range tests {
+		row := carryForwardRow(test.spec, columns, 0, 1250.5, test.label, "À reporter")
+		if got := strings.Join(row.Cells, "|"); got != test.want {
+			t.Errorf("%s : %q, attendu %q", test.name, got, test.want)
+		}
+		if row.kind != rowCarryForward {
+			t.Errorf("%s : ligne de type %d, attendu une ligne de report", test.name, row.kind)
+		}
+		if test.style != nil && row.Style != test.style {
+			t.Errorf("%s : style %+v, attendu le style déclaré", test.name, row.Style)
+		}
+		if test.style == nil && (row.Style == nil || !row.Style.Bold || !row.Style.Italic) {
+			t.Errorf("%s : style %+v, attendu gras italique", test.name, row.Style)
+		}
+	}
+}
+
+func TestCarryForwardFragments(t *testing.T) {
+	var items []interface{}
+	for i := 1; i <= 80; i++ {
+		items = append(items, map[string]interface{}{"label": fmt.Sprintf("Ligne %d", i), "amount": float64(i)})
+	}
+	template := Template{Elements: []Element{{
+		Type:       "table",
+		DataSource: "items",
+		Columns: []TableColumn{
+			{Header: "Libellé", Width: 100, Field: "label"},
+			{Header: "Montant", Width: 40, Field: "amount", Format: "%.2f"},
+		},
+		CarryForward: &TableCarryForward{Field: "amount", TopLabel: "Übertrag"},
+	}}}
+	builder := NewPDFBuilder(template)
+	builder.vars = map[string]interface{}{"items": items}
+	boxes := builder.Layout()
+	checkBoxesInPage(t, builder, boxes)
+	if len(boxes) < 3 {
+		t.Fatalf("%d fragment(s), attendu au moins 3", len(boxes))
+	}
+
+	// Cumul des montants 1 à n
+	sum := func(n int) string { return fmt.Sprintf("%.2f", float64(n*(n+1)/2)) }
+	for i, box := range boxes {
+		rows := fragmentRows(box.Element, box.rows, box.From, box.To)
+		if box.Page != i+1 {
+			t.Errorf("fragment %d en page %d", i, box.Page)
+		}
+		// Hauteur réservée par la mise en page : en-têtes répétés et lignes de report comprises
+		if height := float64(len(rows)+1) * tableRowHeight; math.Abs(box.Height-height) > 1e-9 {
+			t.Errorf("fragment %d : %v mm réservés pour %d lignes dessinées", i, box.Height, len(rows)+1)
+		}
+
+		first, last := rows[0], rows[len(rows)-1]
+		if i > 0 {
+			if got := strings.Join(first.Cells, "|"); first.kind != rowCarryForward || got != "Übertrag|"+sum(box.From) {
+				t.Errorf("fragment %d : première ligne %q, attendu le report de %s", i, got, sum(box.From))
+			}
+		} else if first.kind == rowCarryForward {
+			t.Error("report en haut du premier fragment")
+		}
+		if i < len(boxes)-1 {
+			if got := strings.Join(last.Cells, "|"); last.kind != rowCarryForward || got != "À reporter|"+sum(box.To) {
+				t.Errorf("fragment %d : dernière ligne %q, attendu « À reporter » %s", i, got, sum(box.To))
+			}
+		} else if last.kind == rowCarryForward {
+			t.Error("« À reporter » en bas du dernier fragment")
+		}
+	}
+}
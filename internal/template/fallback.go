@@ -0,0 +1,257 @@
+package template
+
+import (
+	"encoding/binary"
+	"strings"
+	"unicode"
+)
+
+// --- Polices de secours (caractères absents de la police) ---
+//
+// fonts.fallbacks associe à une famille une liste de polices UTF-8 à essayer, dans
+// l'ordre, pour les caractères qu'elle ne contient pas (idéogrammes CJK, symboles...).
+// Les textes sont découpés en runs selon la couverture de chaque police : un caractère
+// absent de la police du texte est écrit avec la première police de secours qui le
+// contient, ou reste dans la police du texte si aucune ne le contient. La couverture
+// d'une police UTF-8 est lue dans sa table cmap (format 4, plan multilingue de base :
+// gofpdf ne sait pas écrire les caractères au-delà) ; celle d'une police 8 bits est
+// l'ensemble des caractères de sa page de codes.
+
+// glyphCoverage est l'ensemble des caractères (plan de base) ayant un glyphe dans une police
+type glyphCoverage []uint64
+
+// has indique si la police contient un glyphe pour le caractère
+func (c glyphCoverage) has(r rune) bool {
+	if r < 0 || r > 0xFFFF || len(c) == 0 {
+		return false
+	}
+	return c[r>>6]&(1<<(uint(r)&63)) != 0
+}
+
+// parseCoverage lit la table cmap Unicode (format 4) d'une police TrueType ; nil si elle est illisible
+func parseCoverage(data []byte) glyphCoverage {
+	u16 := func(offset int) int {
+		if offset < 0 || offset+2 > len(data) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(data[offset:]))
+	}
+	u32 := func(offset int) int {
+		if offset < 0 || offset+4 > len(data) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint32(data[offset:]))
+	}
+
+	// Répertoire des tables
+	cmap := -1
+	for i := 0; i < u16(4); i++ {
+		entry := 12 + 16*i
+		if entry+16 <= len(data) && string(data[entry:entry+4]) == "cmap" {
+			cmap = u32(entry + 8)
+		}
+	}
+	if cmap < 0 {
+		return nil
+	}
+
+	// Sous-table Unicode au format 4 : Windows (3, 1) ou Unicode (0, x)
+	table := -1
+	for i := 0; i < u16(cmap+2); i++ {
+		record := cmap + 4 + 8*i
+		platform, encoding := u16(record), u16(record+2)
+		offset := cmap + u32(record+4)
+		if ((platform == 3 && encoding == 1) || platform == 0) && u16(offset) == 4 {
+			table = offset
+			break
+		}
+	}
+	if table < 0 {
+		return nil
+	}
+
+	coverage := make(glyphCoverage, 0x10000/64)
+	segments := u16(table+6) / 2
+	ends := table + 14
+	starts := ends + 2*segments + 2
+	deltas := starts + 2*segments
+	rangeOffsets := deltas + 2*segments
+	for s := 0; s < segments; s++ {
+		start, end := u16(starts+2*s), u16(ends+2*s)
+		delta, rangeOffset := u16(deltas+2*s), u16(rangeOffsets+2*s)
+		for c := start; c <= end && c < 0xFFFF; c++ {
+			glyph := (c + delta) & 0xFFFF
+			if rangeOffset != 0 {
+				glyph = u16(rangeOffsets + 2*s + rangeOffset + 2*(c-start))
+				if glyph != 0 {
+					glyph = (glyph + delta) & 0xFFFF
+				}
+			}
+			if glyph != 0 {
+				coverage[c>>6] |= 1 << (uint(c) & 63)
+			}
+		}
+	}
+	return coverage
+}
+
+// fontSegment est une partie de texte écrite avec une même police
+type fontSegment struct {
+	family string
+	text   string
+}
+
+// covers indique si une famille contient un caractère : selon sa table cmap pour une police
+// UTF-8 (couverture inconnue : oui), selon sa page de codes pour une police 8 bits
+func (b *PDFBuilder) covers(family string, r rune) bool {
+	key := strings.ToLower(family)
+	if b.utf8Fonts[key] {
+		coverage, ok := b.coverage[key]
+		return !ok || coverage.has(r)
+	}
+	return r < 0x80 || b.encodeFor(family, string(r)) != "."
+}
+
+// fallbackFonts retourne les polices de secours chargées d'une famille
+func (b *PDFBuilder) fallbackFonts(family string) []string {
+	var fonts []string
+	for name, list := range b.config.Fonts.Fallbacks {
+		if !strings.EqualFold(name, family) {
+			continue
+		}
+		for _, font := range list {
+			if b.utf8Fonts[strings.ToLower(font)] {
+				fonts = append(fonts, font)
+			}
+		}
+	}
+	return fonts
+}
+
+// fontSegments découpe un texte selon la police qui contient chaque caractère ; les espaces et
+// les marques combinantes restent dans la police du caractère qui les précède
+func (b *PDFBuilder) fontSegments(family, text string) []fontSegment {
+	fallbacks := b.fallbackFonts(family)
+	if len(fallbacks) == 0 {
+		return []fontSegment{{family, text}}
+	}
+
+	var segments []fontSegment
+	current, start := "", 0
+	for i, r := range text {
+		font := current
+		if current == "" || !(unicode.IsSpace(r) || unicode.Is(unicode.Mn, r)) {
+			font = family
+			if !b.covers(family, r) {
+				for _, fallback := range fallbacks {
+					if b.covers(fallback, r) {
+						font = fallback
+						break
+					}
+				}
+			}
+		}
+		if font != current {
+			if i > start {
+				segments = append(segments, fontSegment{current, text[start:i]})
+			}
+			current, start = font, i
+		}
+	}
+	if start < len(text) || len(segments) == 0 {
+		if current == "" {
+			current = family
+		}
+		segments = append(segments, fontSegment{current, text[start:]})
+	}
+	return segments
+}
+
+// needsFallback indique si un texte contient des caractères à écrire avec une police de secours
+func (b *PDFBuilder) needsFallback(family, text string) bool {
+	segments := b.fontSegments(family, text)
+	return len(segments) > 1 || segments[0].family != family
+}
+
+// styleFamily retourne la famille de police d'un style
+func (b *PDFBuilder) styleFamily(style *Style) string {
+	if style != nil && style.Font != "" {
+		return style.Font
+	}
+	return b.config.Fonts.Default
+}
+
+// fallbackRuns découpe les runs dont des caractères manquent dans leur police : chaque partie
+// reçoit une copie du style avec la police de secours
+func (b *PDFBuilder) fallbackRuns(runs []textRun) []textRun {
+	var split []textRun
+	for _, run := range runs {
+		family := b.styleFamily(run.style)
+		segments := b.fontSegments(family, run.text)
+		if len(segments) == 1 && segments[0].family == family {
+			split = append(split, run)
+			continue
+		}
+		for _, segment := range segments {
+			style := run.style
+			if segment.family != family {
+				copied := *run.style
+				copied.Font = segment.family
+				style = &copied
+			}
+			split = append(split, textRun{text: segment.text, style: style, link: run.link})
+		}
+	}
+	return split
+}
+
+// drawTextCell écrit une cellule sur une ligne comme CellFormat, en passant aux polices de
+// secours pour les caractères absents de la police courante
+func (b *PDFBuilder) drawTextCell(width, height float64, content, direction, border string, ln int, align string, fill bool) {
+	text := content
+	if b.bidiApplies(content, direction) {
+		text = b.visualText(content, direction)
+	}
+	segments := b.fontSegments(b.font.family, text)
+	if len(segments) == 1 && segments[0].family == b.font.family {
+		b.pdf.CellFormat(width, height, b.visualText(content, direction), border, ln, align, fill, 0, "")
+		return
+	}
+
+	saved := b.font
+	x, y := b.pdf.GetXY()
+
+	// Cadre et fond, puis chaque partie dans sa police, sans marge intérieure
+	b.pdf.CellFormat(width, height, "", border, ln, "", fill, 0, "")
+	endX, endY := b.pdf.GetXY()
+
+	widths := make([]float64, len(segments))
+	total := 0.0
+	for i, segment := range segments {
+		b.setFont(segment.family, saved.style, saved.size)
+		segments[i].text = b.encodeFor(segment.family, segment.text)
+		widths[i] = b.pdf.GetStringWidth(segments[i].text)
+		total += widths[i]
+	}
+
+	margin := b.pdf.GetCellMargin()
+	segmentX := x + margin
+	switch align {
+	case "C":
+		segmentX = x + (width-total)/2
+	case "R":
+		segmentX = x + width - margin - total
+	}
+
+	b.pdf.SetCellMargin(0)
+	for i, segment := range segments {
+		b.setFont(segment.family, saved.style, saved.size)
+		b.pdf.SetXY(segmentX, y)
+		b.pdf.CellFormat(widths[i], height, segment.text, "", 0, "L", false, 0, "")
+		segmentX += widths[i]
+	}
+	b.pdf.SetCellMargin(margin)
+
+	b.setFont(saved.family, saved.style, saved.size)
+	b.pdf.SetXY(endX, endY)
+}
@@ -0,0 +1,131 @@
+package template
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+// cmapSegment est un segment d'une sous-table cmap au format 4
+type cmapSegment struct {
+	start, end uint16
+	delta      int      // idDelta (modulo 65536)
+	glyphs     []uint16 // glyphes par idRangeOffset (idDelta ajouté) ; nil : code + idDelta
+}
+
+// cmapFont écrit une police réduite à une table cmap : une sous-table au format 4 pour la
+// plateforme et l'encodage donnés
+func cmapFont(platform, encoding uint16, segments []cmapSegment) []byte {
+	u16s := func(values ...uint16) []byte {
+		var out []byte
+		for _, v := range values {
+			out = binary.BigEndian.AppendUint16(out, v)
+		}
+		return out
+	}
+
+	count := len(segments)
+	var ends, starts, deltas, rangeOffsets, glyphs []uint16
+	for s, segment := range segments {
+		ends = append(ends, segment.end)
+		starts = append(starts, segment.start)
+		deltas = append(deltas, uint16(segment.delta))
+		offset := uint16(0)
+		if segment.glyphs != nil {
+			// Depuis l'entrée idRangeOffset du segment jusqu'à ses glyphes
+			offset = uint16(2 * (count - s + len(glyphs)))
+			glyphs = append(glyphs, segment.glyphs...)
+		}
+		rangeOffsets = append(rangeOffsets, offset)
+	}
+
+	subtable := u16s(4, 0, 0, uint16(2*count), 0, 0, 0)
+	subtable = append(subtable, u16s(ends...)...)
+	subtable = append(subtable, u16s(0)...)
+	subtable = append(subtable, u16s(starts...)...)
+	subtable = append(subtable, u16s(deltas...)...)
+	subtable = append(subtable, u16s(rangeOffsets...)...)
+	subtable = append(subtable, u16s(glyphs...)...)
+	binary.BigEndian.PutUint16(subtable[2:], uint16(len(subtable)))
+
+	cmap := append(u16s(0, 1, platform, encoding, 0, 12), subtable...)
+	return writeSFNT(sfntTrueType, []sfntTable{{"cmap", cmap}})
+}
+
+func TestParseCoverage(t *testing.T) {
+	segments := []cmapSegment{
+		{start: 'A', end: 'C', delta: 1 - 'A'},               // glyphes 1 à 3
+		{start: 0x20AC, end: 0x20AD, glyphs: []uint16{5, 0}}, // € : glyphe 5, 0x20AD : glyphe 0
+		{start: 0x0400, end: 0x0401, delta: 0 - 0x0400},      // 0x0400 : glyphe 0
+		{start: 0xFFFF, end: 0xFFFF, delta: 1},               // fin de table
+	}
+	windows := parseCoverage(cmapFont(3, 1, segments))
+	unicode := parseCoverage(cmapFont(0, 3, segments))
+	if windows == nil || unicode == nil {
+		t.Fatal("table cmap au format 4 non lue")
+	}
+
+	tests := []struct {
+		r    rune
+		want bool
+	}{
+		{'A', true},
+		{'C', true},
+		{'D', false},
+		{'€', true},
+		{0x20AD, false}, // glyphe 0 par idRangeOffset
+		{0x0400, false}, // glyphe 0 par idDelta
+		{0x0401, true},
+		{0xFFFF, false},
+		{0x1F600, false}, // hors du plan de base
+		{-1, false},
+	}
+	for _, test := range tests {
+		if got := windows.has(test.r); got != test.want {
+			t.Errorf("(3, 1) %U : %v, attendu %v", test.r, got, test.want)
+		}
+		if got := unicode.has(test.r); got != test.want {
+			t.Errorf("(0, 3) %U : %v, attendu %v", test.r, got, test.want)
+		}
+	}
+}
+
+func TestParseCoverageUnreadable(t *testing.T) {
+	valid := cmapFont(3, 1, []cmapSegment{{start: 'A', end: 'Z', delta: 1 - 'A'}, {start: 0xFFFF, end: 0xFFFF, delta: 1}})
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"vide", nil},
+		{"sans cmap", writeSFNT(sfntTrueType, []sfntTable{{"head", make([]byte, 54)}})},
+		{"cmap symbole (3, 0)", cmapFont(3, 0, []cmapSegment{{start: 'A', end: 'Z', delta: 1 - 'A'}})},
+		{"répertoire tronqué", valid[:20]},
+	}
+	for _, test := range tests {
+		if coverage := parseCoverage(test.data); coverage != nil {
+			t.Errorf("%s : couverture lue, attendu nil", test.name)
+		}
+	}
+
+	// Sous-table tronquée : lecture sans panique, caractères au-delà des données absents
+	truncated := parseCoverage(valid[:len(valid)-8])
+	if truncated.has('é') {
+		t.Error("sous-table tronquée : é couvert")
+	}
+}
+
+func TestParseCoverageDejaVu(t *testing.T) {
+	data, err := os.ReadFile("fontpack/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	coverage := parseCoverage(data)
+	for _, test := range []struct {
+		r    rune
+		want bool
+	}{{'A', true}, {'é', true}, {'€', true}, {'Ж', true}, {'ب', true}, {'中', false}} {
+		if got := coverage.has(test.r); got != test.want {
+			t.Errorf("DejaVu Sans %q : %v, attendu %v", test.r, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package template
+
+// --- Ajustement automatique de la taille du texte (style.fit) ---
+//
+// La taille de police est réduite par pas de fitStep jusqu'à ce que le texte tienne
+// en fit.maxLines lignes dans sa largeur et dans la hauteur disponible (ligne de
+// tableau, cadre de hauteur imposée ou page), sans descendre sous fit.minSize. À la taille minimale, le
+// texte est rendu normalement, quitte à occuper plus de lignes.
+
+const (
+	fitStep    = 0.5 // pas de réduction de la taille de police (pt)
+	fitMinSize = 6.0 // taille minimale par défaut (pt)
+)
+
+// TextFit réduit la taille de police d'un texte trop long pour sa zone
+type TextFit struct {
+	MaxLines int     `json:"maxLines,omitempty"` // nombre de lignes maximal (défaut 1)
+	MinSize  float64 `json:"minSize,omitempty"`  // taille de police minimale en pt (défaut 6)
+}
+
+// fitSize retourne la plus grande taille, de size à fit.minSize, pour laquelle le texte tient
+// en maxLines lignes selon fits ; une taille déjà inférieure à fit.minSize est conservée
+func fitSize(fit *TextFit, size float64, fits func(size float64, maxLines int) bool) float64 {
+	maxLines := 1
+	if fit.MaxLines > 0 {
+		maxLines = fit.MaxLines
+	}
+	minSize := fitMinSize
+	if fit.MinSize > 0 {
+		minSize = fit.MinSize
+	}
+
+	if size <= minSize {
+		return size
+	}
+	for ; size > minSize; size -= fitStep {
+		if fits(size, maxLines) {
+			return size
+		}
+	}
+	return minSize
+}
+
+// fitText applique style.fit à un élément texte pour la largeur de contenu donnée et la hauteur
+// de la zone courante : l'élément retourné porte une copie du style à la taille ajustée
+func (b *PDFBuilder) fitText(element Element, width float64) Element {
+	if element.Style == nil || element.Style.Fit == nil {
+		return element
+	}
+
+	style := *element.Style
+	element.Style = &style
+	size := 10.0
+	if style.Size > 0 {
+		size = style.Size
+	}
+
+	height := b.areaHeight()
+	style.Size = fitSize(style.Fit, size, func(size float64, maxLines int) bool {
+		style.Size = size
+		lines := b.textLineCount(element, width)
+		return lines <= maxLines && float64(lines)*textLineHeight(&style, 8) <= height+1e-9
+	})
+	return element
+}
+
+// areaHeight retourne la hauteur disponible dans la zone courante : intérieur d'un cadre de
+// hauteur imposée, sinon hauteur de contenu de la page
+func (b *PDFBuilder) areaHeight() float64 {
+	if b.area.height > 0 {
+		return b.area.height
+	}
+	_, pageHeight := b.pdf.GetPageSize()
+	return pageHeight - b.margins.top - b.margins.bottom
+}
+
+// textLineCount retourne le nombre de lignes d'un texte dans la largeur de contenu donnée
+func (b *PDFBuilder) textLineCount(element Element, width float64) int {
+	switch {
+	case b.richLayout(element):
+		return len(b.richLines(element, width))
+	case noWrap(element.Style):
+		return 1
+	}
+	return b.countLines(element.Style, textContent(element), width)
+}
+
+// drawFitCell écrit une cellule de tableau avec la police courante réduite selon fit : le texte
+// tient en fit.maxLines lignes dans la largeur et la hauteur de la cellule. La position finale
+// est celle de CellFormat (à droite de la cellule).
+func (b *PDFBuilder) drawFitCell(fit *TextFit, width, height float64, content, direction, border, align string, fill bool) {
+	saved := b.font
+	defer b.setFont(saved.family, saved.style, saved.size)
+
+	// Texte de droite à gauche : découpé dans l'ordre logique, chaque ligne réordonnée ensuite
+	bidi := b.bidiApplies(content, direction)
+	text := b.encode(content)
+	if bidi {
+		text = shapeArabic(content)
+	}
+
+	// Les largeurs sont proportionnelles à la taille : mesurer à la taille courante dans une
+	// largeur agrandie évite de changer de police à chaque essai
+	margin := 2 * b.pdf.GetCellMargin()
+	size := fitSize(fit, saved.size, func(size float64, maxLines int) bool {
+		lines := len(b.wrapLines(text, margin+(width-margin)*saved.size/size))
+		// Interligne minimal de 1,2 × la taille (pt -> mm)
+		return lines <= maxLines && float64(lines)*size*0.3528*1.2 <= height
+	})
+	b.setFont(saved.family, saved.style, size)
+
+	lines := b.wrapLines(text, width)
+	if bidi {
+		for i, line := range lines {
+			lines[i] = b.visualText(line, direction)
+		}
+		text = lines[0]
+	}
+	if len(lines) <= 1 {
+		b.pdf.CellFormat(width, height, text, border, 0, align, fill, 0, "")
+		return
+	}
+
+	// Plusieurs lignes : cadre et fond sur toute la cellule, lignes réparties sur la hauteur
+	x, y := b.pdf.GetXY()
+	b.pdf.CellFormat(width, height, "", border, 0, align, fill, 0, "")
+	lineHeight := height / float64(len(lines))
+	for i, line := range lines {
+		b.pdf.SetXY(x, y+float64(i)*lineHeight)
+		b.pdf.CellFormat(width, lineHeight, line, "", 0, align, false, 0, "")
+	}
+	b.pdf.SetXY(x+width, y)
+}
@@ -0,0 +1,103 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		fit   TextFit
+		size  float64
+		limit float64 // le texte tient à partir de cette taille
+		want  float64
+	}{
+		{"tient déjà", TextFit{}, 14, 20, 14},
+		{"pas de 0,5 pt", TextFit{}, 14, 10, 10},
+		{"taille entre deux pas", TextFit{}, 14, 9.8, 9.5},
+		{"minimum par défaut", TextFit{}, 14, 1, 6},
+		{"minimum précisé", TextFit{MinSize: 8}, 14, 1, 8},
+		{"taille déjà sous le minimum", TextFit{MinSize: 8}, 7, 1, 7},
+	}
+	for _, test := range tests {
+		got := fitSize(&test.fit, test.size, func(size float64, maxLines int) bool { return size <= test.limit })
+		if got != test.want {
+			t.Errorf("%s : %v pt, attendu %v pt", test.name, got, test.want)
+		}
+	}
+
+	for _, test := range []struct{ maxLines, want int }{{0, 1}, {3, 3}} {
+		fitSize(&TextFit{MaxLines: test.maxLines}, 10, func(size float64, maxLines int) bool {
+			if maxLines != test.want {
+				t.Errorf("maxLines %d : %d ligne(s), attendu %d", test.maxLines, maxLines, test.want)
+			}
+			return true
+		})
+	}
+}
+
+func TestFitText(t *testing.T) {
+	builder := NewPDFBuilder(Template{})
+	content := strings.Repeat("Nom de produit très long ", 4)
+	fitted := func(fit *TextFit, lineHeight float64, width float64) Element {
+		element := Element{Type: "text", Content: content, Style: &Style{Size: 20, LineHeight: lineHeight, Fit: fit}}
+		return builder.fitText(element, width)
+	}
+	lines := func(element Element, width float64) int { return builder.textLineCount(element, width) }
+	larger := func(element Element) Element {
+		style := *element.Style
+		style.Size += fitStep
+		element.Style = &style
+		return element
+	}
+
+	// Largeur : la plus grande taille qui tient en maxLines lignes
+	element := fitted(&TextFit{MaxLines: 2}, 0, 80)
+	if size := element.Style.Size; size >= 20 || lines(element, 80) > 2 || lines(larger(element), 80) <= 2 {
+		t.Errorf("largeur : %v pt sur %d ligne(s), attendu la plus grande taille sur 2 lignes", size, lines(element, 80))
+	}
+
+	// Hauteur de la zone : 3 lignes autorisées, mais seulement 10 mm de haut
+	free := fitted(&TextFit{MaxLines: 3}, 1.2, 80)
+	builder.area.height = 10
+	bounded := fitted(&TextFit{MaxLines: 3}, 1.2, 80)
+	builder.area.height = 0
+	height := func(element Element) float64 {
+		return float64(lines(element, 80)) * textLineHeight(element.Style, 8)
+	}
+	if bounded.Style.Size >= free.Style.Size || height(bounded) > 10 || height(larger(bounded)) <= 10 && lines(larger(bounded), 80) <= 3 {
+		t.Errorf("hauteur : %v pt (%v mm), %v pt sans borne, attendu la plus grande taille tenant en 10 mm",
+			bounded.Style.Size, height(bounded), free.Style.Size)
+	}
+
+	// Taille minimale atteinte : texte rendu sur plus de lignes
+	if element := fitted(&TextFit{MinSize: 12}, 0, 20); element.Style.Size != 12 {
+		t.Errorf("minimum : %v pt, attendu 12 pt", element.Style.Size)
+	}
+
+	// Sans fit : élément inchangé
+	plain := Element{Type: "text", Content: content, Style: &Style{Size: 20}}
+	if builder.fitText(plain, 20).Style != plain.Style {
+		t.Error("style copié sans fit")
+	}
+}
+
+func TestFitTextInFixedBox(t *testing.T) {
+	// Étiquette de 12 mm de haut : le texte est réduit pour tenir dans le cadre
+	text := Element{Type: "text", Content: strings.Repeat("Client au nom très long ", 6),
+		Style: &Style{Size: 16, LineHeight: 1.2, Fit: &TextFit{MaxLines: 4}}}
+	template := Template{Elements: []Element{{
+		Type: "box", Style: &Style{Width: 60, Height: 12, Padding: []float64{1}}, Children: []Element{text},
+	}}}
+	builder := NewPDFBuilder(template)
+	boxes := builder.Layout()
+	for _, box := range boxes {
+		if box.Element.Type == "text" && box.Height > 10+1e-9 {
+			t.Errorf("texte de %v mm (%v pt) dans un cadre de 10 mm intérieurs", box.Height, box.Element.Style.Size)
+		}
+	}
+	if _, err := builder.Build(); err != nil {
+		t.Fatal(err)
+	}
+}
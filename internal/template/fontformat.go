@@ -0,0 +1,310 @@
+package template
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// --- Formats de police : TrueType, OpenType (CFF), WOFF et WOFF2 ---
+//
+// gofpdf ne lit que les polices TrueType (contours glyf). Les autres formats sont
+// convertis en TrueType à l'enregistrement (voir registerFont), selon la signature du
+// fichier : WOFF (tables compressées zlib) et WOFF2 (brotli, tables glyf/loca/hmtx
+// transformées) sont décompressés, puis les contours CFF d'une police OpenType (.otf)
+// sont convertis en contours TrueType (voir cff.go). Tout est en Go pur, donc disponible
+// aussi en wasip1. Un fichier de signature inconnue est laissé tel quel.
+
+// Signatures des fichiers de police (4 premiers octets)
+const (
+	sfntTrueType   = 0x00010000
+	sfntOpenType   = 0x4F54544F // "OTTO" : contours CFF
+	sfntCollection = 0x74746366 // "ttcf"
+	woffSignature  = 0x774F4646 // "wOFF"
+	woff2Signature = 0x774F4632 // "wOF2"
+)
+
+// maxFontSize est la taille maximale d'une police décompressée (WOFF, WOFF2) : les tailles lues
+// dans l'en-tête ne doivent pas permettre à un petit fichier de réserver beaucoup de mémoire
+const maxFontSize = 128 << 20
+
+// sfntTable est une table d'une police sfnt (TrueType ou OpenType)
+type sfntTable struct {
+	tag  string
+	data []byte
+}
+
+// trueTypeFont retourne une police au format TrueType, en décompressant WOFF/WOFF2 et en
+// convertissant les contours CFF ; les données déjà TrueType ou inconnues sont retournées telles quelles
+func trueTypeFont(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return data, nil
+	}
+
+	var err error
+	switch binary.BigEndian.Uint32(data) {
+	case woffSignature:
+		if data, err = decodeWOFF(data); err != nil {
+			return nil, fmt.Errorf("woff: %w", err)
+		}
+	case woff2Signature:
+		if data, err = decodeWOFF2(data); err != nil {
+			return nil, fmt.Errorf("woff2: %w", err)
+		}
+	}
+
+	switch binary.BigEndian.Uint32(data) {
+	case sfntOpenType:
+		if data, err = convertCFF(data); err != nil {
+			return nil, fmt.Errorf("cff: %w", err)
+		}
+	case sfntCollection:
+		return nil, fmt.Errorf("les collections de polices (ttc) ne sont pas gérées")
+	}
+	return data, nil
+}
+
+// readSFNT lit le répertoire des tables d'une police sfnt
+func readSFNT(data []byte) (uint32, []sfntTable, error) {
+	if len(data) < 12 {
+		return 0, nil, fmt.Errorf("police tronquée")
+	}
+	version := binary.BigEndian.Uint32(data)
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+16*count {
+		return 0, nil, fmt.Errorf("répertoire des tables tronqué")
+	}
+
+	tables := make([]sfntTable, 0, count)
+	for i := 0; i < count; i++ {
+		entry := data[12+16*i:]
+		offset, length := binary.BigEndian.Uint32(entry[8:]), binary.BigEndian.Uint32(entry[12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return 0, nil, fmt.Errorf("table %q hors du fichier", entry[:4])
+		}
+		tables = append(tables, sfntTable{string(entry[:4]), data[offset : offset+length]})
+	}
+	return version, tables, nil
+}
+
+// writeSFNT assemble une police sfnt : répertoire trié par tag, tables alignées sur 4 octets,
+// sommes de contrôle et ajustement de la table head
+func writeSFNT(version uint32, tables []sfntTable) []byte {
+	sort.Slice(tables, func(i, j int) bool { return tables[i].tag < tables[j].tag })
+
+	count := len(tables)
+	power := 1
+	for power*2 <= count {
+		power *= 2
+	}
+	selector := 0
+	for 1<<(selector+1) <= power {
+		selector++
+	}
+
+	var out bytes.Buffer
+	header := make([]byte, 12+16*count)
+	binary.BigEndian.PutUint32(header, version)
+	binary.BigEndian.PutUint16(header[4:], uint16(count))
+	binary.BigEndian.PutUint16(header[6:], uint16(power*16))
+	binary.BigEndian.PutUint16(header[8:], uint16(selector))
+	binary.BigEndian.PutUint16(header[10:], uint16(count*16-power*16))
+	out.Write(header)
+
+	headOffset := -1
+	for i, table := range tables {
+		data := table.data
+		if table.tag == "head" && len(data) >= 12 {
+			// checkSumAdjustment recalculé une fois la police assemblée
+			data = append([]byte(nil), data...)
+			binary.BigEndian.PutUint32(data[8:], 0)
+			headOffset = out.Len()
+		}
+		entry := header[12+16*i:]
+		copy(entry, table.tag)
+		binary.BigEndian.PutUint32(entry[4:], sfntChecksum(data))
+		binary.BigEndian.PutUint32(entry[8:], uint32(out.Len()))
+		binary.BigEndian.PutUint32(entry[12:], uint32(len(data)))
+		out.Write(data)
+		for out.Len()%4 != 0 {
+			out.WriteByte(0)
+		}
+	}
+
+	font := out.Bytes()
+	copy(font, header)
+	if headOffset >= 0 {
+		binary.BigEndian.PutUint32(font[headOffset+8:], 0xB1B0AFBA-sfntChecksum(font))
+	}
+	return font
+}
+
+// sfntChecksum calcule la somme de contrôle d'une table (mots de 32 bits, complétée par des zéros)
+func sfntChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// decodeWOFF reconstitue la police sfnt d'un fichier WOFF (tables compressées par zlib)
+func decodeWOFF(data []byte) ([]byte, error) {
+	if len(data) < 44 {
+		return nil, fmt.Errorf("en-tête tronqué")
+	}
+	flavor := binary.BigEndian.Uint32(data[4:])
+	count := int(binary.BigEndian.Uint16(data[12:]))
+	if len(data) < 44+20*count {
+		return nil, fmt.Errorf("répertoire des tables tronqué")
+	}
+
+	tables := make([]sfntTable, 0, count)
+	total := uint64(0)
+	for i := 0; i < count; i++ {
+		entry := data[44+20*i:]
+		offset := binary.BigEndian.Uint32(entry[4:])
+		compLength := binary.BigEndian.Uint32(entry[8:])
+		origLength := binary.BigEndian.Uint32(entry[12:])
+		if uint64(offset)+uint64(compLength) > uint64(len(data)) || compLength > origLength {
+			return nil, fmt.Errorf("table %q invalide", entry[:4])
+		}
+		if total += uint64(origLength); total > maxFontSize {
+			return nil, fmt.Errorf("police de plus de %d octets une fois décompressée", maxFontSize)
+		}
+
+		table := data[offset : offset+compLength]
+		if compLength < origLength {
+			reader, err := zlib.NewReader(bytes.NewReader(table))
+			if err != nil {
+				return nil, fmt.Errorf("table %q : %w", entry[:4], err)
+			}
+			// Lecture limitée : la mémoire suit les données décompressées, pas la longueur annoncée
+			if table, err = io.ReadAll(io.LimitReader(reader, int64(origLength)+1)); err != nil {
+				return nil, fmt.Errorf("table %q : %w", entry[:4], err)
+			}
+			if len(table) != int(origLength) {
+				return nil, fmt.Errorf("table %q : %d octets décompressés, attendu %d", entry[:4], len(table), origLength)
+			}
+		}
+		tables = append(tables, sfntTable{string(entry[:4]), table})
+	}
+	return writeSFNT(flavor, tables), nil
+}
+
+// findTable retourne une table d'après son tag, nil si elle est absente
+func findTable(tables []sfntTable, tag string) []byte {
+	for _, table := range tables {
+		if table.tag == tag {
+			return table.data
+		}
+	}
+	return nil
+}
+
+// glyphPoint est un point d'un contour TrueType
+type glyphPoint struct {
+	x, y    int
+	onCurve bool
+}
+
+// encodeSimpleGlyph écrit un glyphe simple de la table glyf ; bbox (xMin, yMin, xMax, yMax) est
+// calculée d'après les points si elle n'est pas fournie
+func encodeSimpleGlyph(contours [][]glyphPoint, instructions []byte, bbox *[4]int, overlap bool) []byte {
+	if len(contours) == 0 {
+		return nil
+	}
+
+	if bbox == nil {
+		bbox = &[4]int{contours[0][0].x, contours[0][0].y, contours[0][0].x, contours[0][0].y}
+		for _, contour := range contours {
+			for _, point := range contour {
+				bbox[0], bbox[1] = min(bbox[0], point.x), min(bbox[1], point.y)
+				bbox[2], bbox[3] = max(bbox[2], point.x), max(bbox[3], point.y)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	put16 := func(value int) {
+		out.WriteByte(byte(value >> 8))
+		out.WriteByte(byte(value))
+	}
+	put16(len(contours))
+	for _, value := range bbox {
+		put16(value)
+	}
+	end := -1
+	for _, contour := range contours {
+		end += len(contour)
+		put16(end)
+	}
+	put16(len(instructions))
+	out.Write(instructions)
+
+	// Drapeaux puis coordonnées relatives : 0 ou 1 octet (X_SHORT) quand c'est possible
+	var flags, xs, ys []byte
+	coordinate := func(delta int, short, same byte, values *[]byte) byte {
+		switch {
+		case delta == 0:
+			return same
+		case delta > -256 && delta < 256:
+			if delta > 0 {
+				*values = append(*values, byte(delta))
+				return short | same
+			}
+			*values = append(*values, byte(-delta))
+			return short
+		}
+		*values = append(*values, byte(delta>>8), byte(delta))
+		return 0
+	}
+	x, y := 0, 0
+	for _, contour := range contours {
+		for _, point := range contour {
+			var flag byte
+			if point.onCurve {
+				flag |= 0x01
+			}
+			if overlap && len(flags) == 0 {
+				flag |= 0x40
+			}
+			flag |= coordinate(point.x-x, 0x02, 0x10, &xs)
+			flag |= coordinate(point.y-y, 0x04, 0x20, &ys)
+			flags = append(flags, flag)
+			x, y = point.x, point.y
+		}
+	}
+	out.Write(flags)
+	out.Write(xs)
+	out.Write(ys)
+	return out.Bytes()
+}
+
+// glyfTables assemble les tables glyf et loca (format long) à partir des glyphes encodés
+func glyfTables(glyphs [][]byte) (glyf, loca []byte) {
+	loca = make([]byte, 4*(len(glyphs)+1))
+	for i, glyph := range glyphs {
+		glyf = append(glyf, glyph...)
+		for len(glyf)%4 != 0 {
+			glyf = append(glyf, 0)
+		}
+		binary.BigEndian.PutUint32(loca[4*(i+1):], uint32(len(glyf)))
+	}
+	return glyf, loca
+}
+
+// withLongLoca retourne une copie de la table head déclarant une table loca au format long
+func withLongLoca(head []byte) ([]byte, error) {
+	if len(head) < 54 {
+		return nil, fmt.Errorf("table head tronquée")
+	}
+	head = append([]byte(nil), head...)
+	binary.BigEndian.PutUint16(head[50:], 1)
+	return head, nil
+}
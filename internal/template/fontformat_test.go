@@ -0,0 +1,479 @@
+package template
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// --- Fichiers WOFF et WOFF2 construits pour les tests ---
+
+// woffFile écrit un fichier WOFF, tables compressées zlib quand c'est plus court
+func woffFile(flavor uint32, tables []sfntTable) []byte {
+	header := make([]byte, 44+20*len(tables))
+	binary.BigEndian.PutUint32(header, woffSignature)
+	binary.BigEndian.PutUint32(header[4:], flavor)
+	binary.BigEndian.PutUint16(header[12:], uint16(len(tables)))
+
+	out := bytes.NewBuffer(header)
+	for i, table := range tables {
+		data := table.data
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		writer.Write(data)
+		writer.Close()
+		if compressed.Len() < len(data) {
+			data = compressed.Bytes()
+		}
+
+		entry := out.Bytes()[44+20*i:]
+		copy(entry, table.tag)
+		binary.BigEndian.PutUint32(entry[4:], uint32(out.Len()))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(data)))
+		binary.BigEndian.PutUint32(entry[12:], uint32(len(table.data)))
+		out.Write(data)
+		for out.Len()%4 != 0 {
+			out.WriteByte(0)
+		}
+	}
+	font := out.Bytes()
+	binary.BigEndian.PutUint32(font[8:], uint32(len(font)))
+	return font
+}
+
+// woff2Entry est une table d'un fichier WOFF2 de test, transformée ou non
+type woff2Entry struct {
+	tag         string
+	data        []byte
+	transformed bool
+}
+
+// woff2File écrit un fichier WOFF2 ; totalSfntSize 0 est remplacé par la taille des tables
+func woff2File(flavor, totalSfntSize uint32, entries []woff2Entry) []byte {
+	base128 := func(value uint32) []byte {
+		out := []byte{byte(value & 0x7F)}
+		for value >>= 7; value > 0; value >>= 7 {
+			out = append([]byte{byte(value&0x7F) | 0x80}, out...)
+		}
+		return out
+	}
+
+	var directory, stream bytes.Buffer
+	for _, entry := range entries {
+		flags := byte(0x3F)
+		for i, tag := range woff2KnownTags {
+			if tag == entry.tag {
+				flags = byte(i)
+			}
+		}
+		// glyf et loca non transformées : version 3 ; autres tables transformées : version 1
+		glyf := entry.tag == "glyf" || entry.tag == "loca"
+		if glyf && !entry.transformed {
+			flags |= 0xC0
+		} else if !glyf && entry.transformed {
+			flags |= 0x40
+		}
+		directory.WriteByte(flags)
+		if flags&0x3F == 0x3F {
+			directory.WriteString(entry.tag)
+		}
+		directory.Write(base128(uint32(len(entry.data))))
+		if entry.transformed {
+			directory.Write(base128(uint32(len(entry.data))))
+		}
+		stream.Write(entry.data)
+	}
+	if totalSfntSize == 0 {
+		totalSfntSize = uint32(12 + 16*len(entries) + stream.Len() + 4*len(entries))
+	}
+
+	var compressed bytes.Buffer
+	writer := brotli.NewWriter(&compressed)
+	writer.Write(stream.Bytes())
+	writer.Close()
+
+	header := make([]byte, 48)
+	binary.BigEndian.PutUint32(header, woff2Signature)
+	binary.BigEndian.PutUint32(header[4:], flavor)
+	binary.BigEndian.PutUint16(header[12:], uint16(len(entries)))
+	binary.BigEndian.PutUint32(header[16:], totalSfntSize)
+	binary.BigEndian.PutUint32(header[20:], uint32(compressed.Len()))
+	font := append(append(header, directory.Bytes()...), compressed.Bytes()...)
+	binary.BigEndian.PutUint32(font[8:], uint32(len(font)))
+	return font
+}
+
+// fontTables lit les tables d'une police de test
+func fontTables(t *testing.T, data []byte) []sfntTable {
+	t.Helper()
+	_, tables, err := readSFNT(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tables
+}
+
+// checkSameTables vérifie que deux polices ont les mêmes tables (hors checkSumAdjustment de head)
+func checkSameTables(t *testing.T, got, want []sfntTable) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%d tables, attendu %d", len(got), len(want))
+	}
+	for _, table := range want {
+		data, expected := findTable(got, table.tag), table.data
+		if table.tag == "head" && len(data) >= 12 && len(expected) >= 12 {
+			data = append(append([]byte(nil), data[:8]...), data[12:]...)
+			expected = append(append([]byte(nil), expected[:8]...), expected[12:]...)
+		}
+		if !bytes.Equal(data, expected) {
+			t.Errorf("table %q différente (%d octets, attendu %d)", table.tag, len(data), len(expected))
+		}
+	}
+}
+
+func TestWOFF(t *testing.T) {
+	original, err := os.ReadFile("fontpack/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := fontTables(t, original)
+
+	converted, err := trueTypeFont(woffFile(sfntTrueType, tables))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameTables(t, fontTables(t, converted), tables)
+}
+
+func TestWOFF2(t *testing.T) {
+	original, err := os.ReadFile("fontpack/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := fontTables(t, original)
+
+	// Tables non transformées : flux brotli des tables telles quelles
+	var entries []woff2Entry
+	for _, table := range tables {
+		entries = append(entries, woff2Entry{table.tag, table.data, false})
+	}
+	converted, err := trueTypeFont(woff2File(sfntTrueType, 0, entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameTables(t, fontTables(t, converted), tables)
+}
+
+// transformedGlyf est une table glyf transformée de deux glyphes : un triangle (10,0),
+// (110,0), (10,100) et un glyphe vide
+func transformedGlyf(flags []byte) []byte {
+	streams := [][]byte{
+		{0, 1, 0, 0},         // Nombres de contours : 1, 0
+		{3},                  // Nombre de points du contour
+		flags,                // Drapeaux des triplets
+		{10, 100, 99, 99, 0}, // Coordonnées, puis longueur des instructions (0)
+		nil,                  // Composites
+		{0, 0, 0, 0},         // Bitmap des boîtes englobantes (aucune)
+		nil,                  // Instructions
+	}
+	header := []byte{0, 0, 0, 0, 0, 2, 0, 0} // reserved, options, numGlyphs, indexFormat
+	for _, stream := range streams {
+		header = binary.BigEndian.AppendUint32(header, uint32(len(stream)))
+	}
+	for _, stream := range streams {
+		header = append(header, stream...)
+	}
+	return header
+}
+
+// triangleFlags codent les déplacements (+10, 0), (+100, 0) et (-100, +100)
+var triangleFlags = []byte{11, 11, 86}
+
+func TestWOFF2TransformedGlyf(t *testing.T) {
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint32(head, 0x00010000)
+	binary.BigEndian.PutUint32(head[12:], 0x5F0F3CF5) // magicNumber
+	binary.BigEndian.PutUint16(head[18:], 1000)       // unitsPerEm
+	hhea := make([]byte, 36)
+	binary.BigEndian.PutUint32(hhea, 0x00010000)
+	binary.BigEndian.PutUint16(hhea[34:], 2) // numberOfHMetrics
+	// hmtx transformée : approches gauches omises (xMin des glyphes), avances 500 et 0
+	hmtx := []byte{1, 0x01, 0xF4, 0, 0}
+
+	font, err := trueTypeFont(woff2File(sfntTrueType, 0, []woff2Entry{
+		{"head", head, false},
+		{"hhea", hhea, false},
+		{"glyf", transformedGlyf(triangleFlags), true},
+		{"loca", nil, true},
+		{"hmtx", hmtx, true},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := fontTables(t, font)
+
+	// Glyphes alignés sur 4 octets dans glyf
+	triangle := encodeSimpleGlyph([][]glyphPoint{{{10, 0, true}, {110, 0, true}, {10, 100, true}}}, nil, nil, false)
+	triangle = append(triangle, make([]byte, -len(triangle)&3)...)
+	tests := []struct {
+		tag  string
+		want []byte
+	}{
+		{"glyf", triangle},
+		{"loca", binary.BigEndian.AppendUint32([]byte{0, 0, 0, 0, 0, 0, 0, byte(len(triangle))}, uint32(len(triangle)))},
+		{"hmtx", []byte{0x01, 0xF4, 0, 10, 0, 0, 0, 0}},
+	}
+	for _, test := range tests {
+		if got := findTable(tables, test.tag); !bytes.Equal(got, test.want) {
+			t.Errorf("table %s : % x, attendu % x", test.tag, got, test.want)
+		}
+	}
+	if format := binary.BigEndian.Uint16(findTable(tables, "head")[50:]); format != 1 {
+		t.Errorf("indexToLocFormat %d, attendu 1 (loca au format long)", format)
+	}
+}
+
+func TestDecodeTriplet(t *testing.T) {
+	tests := []struct {
+		flag   int
+		data   []byte
+		dx, dy int
+	}{
+		{0, []byte{5}, 0, -5},
+		{1, []byte{5}, 0, 5},
+		{3, []byte{5}, 0, 256 + 5},
+		{10, []byte{7}, -7, 0},
+		{11, []byte{7}, 7, 0},
+		{21, []byte{0x12}, 2, -3},       // 1 + 4 bits par coordonnée
+		{86, []byte{99, 99}, -100, 100}, // 1 + 8 bits par coordonnée
+		{123, []byte{0x12, 0x34, 0x56}, 0x123, 0x456},
+		{127, []byte{1, 2, 3, 4}, 0x102, 0x304},
+	}
+	for _, test := range tests {
+		r := &woff2Reader{data: test.data}
+		dx, dy := decodeTriplet(test.flag, r)
+		if dx != test.dx || dy != test.dy || r.err != nil || r.pos != len(test.data) {
+			t.Errorf("triplet %d % x : (%d, %d) %d octet(s) lu(s) %v, attendu (%d, %d)",
+				test.flag, test.data, dx, dy, r.pos, r.err, test.dx, test.dy)
+		}
+	}
+}
+
+func TestFontHeaderErrors(t *testing.T) {
+	original, err := os.ReadFile("fontpack/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []woff2Entry
+	for _, table := range fontTables(t, original) {
+		entries = append(entries, woff2Entry{table.tag, table.data, false})
+	}
+	dejaVuWOFF2 := woff2File(sfntTrueType, 0, entries)
+
+	// withUint32 retourne une copie du fichier avec un entier modifié
+	withUint32 := func(data []byte, offset int, value uint32) []byte {
+		data = append([]byte(nil), data...)
+		binary.BigEndian.PutUint32(data[offset:], value)
+		return data
+	}
+	smallWOFF := woffFile(sfntTrueType, []sfntTable{{"test", bytes.Repeat([]byte("police "), 100)}})
+	head := make([]byte, 54)
+	hhea := make([]byte, 36)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		error string
+	}{
+		{"woff tronqué", smallWOFF[:30], "en-tête tronqué"},
+		{"woff répertoire tronqué", withUint32(smallWOFF[:48], 12, 0x00100000), "répertoire des tables tronqué"},
+		{"woff table hors du fichier", withUint32(smallWOFF, 44+4, 1<<20), "invalide"},
+		{"woff table démesurée", withUint32(smallWOFF, 44+12, 0xFFFFFFFF), "une fois décompressée"},
+		{"woff table plus courte qu'annoncé", withUint32(smallWOFF, 44+12, maxFontSize), "octets décompressés"},
+		{"woff2 tronqué", dejaVuWOFF2[:40], "tronquées"},
+		{"woff2 flux tronqué", dejaVuWOFF2[:len(dejaVuWOFF2)-10], "tronquées"},
+		{"woff2 police démesurée", withUint32(dejaVuWOFF2, 16, maxFontSize+1), "au plus"},
+		{"woff2 flux plus long qu'annoncé", withUint32(dejaVuWOFF2, 16, 1000), "totalSfntSize"},
+		{"woff2 glyphe sans drapeaux", woff2File(sfntTrueType, 0, []woff2Entry{
+			{"head", head, false},
+			{"hhea", hhea, false},
+			{"glyf", transformedGlyf(triangleFlags[:2]), true},
+			{"loca", nil, true},
+		}), "plus de points que de drapeaux"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			_, err := trueTypeFont(test.data)
+			runtime.ReadMemStats(&after)
+
+			if err == nil || !strings.Contains(err.Error(), test.error) {
+				t.Errorf("erreur %v, attendu %q", err, test.error)
+			}
+			// Les tailles annoncées ne réservent pas de mémoire (le décodeur brotli a sa propre fenêtre)
+			if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 32<<20 {
+				t.Errorf("%d octets alloués pour un fichier de %d octets", allocated, len(test.data))
+			}
+		})
+	}
+}
+
+func TestConvertCFF(t *testing.T) {
+	data, err := os.ReadFile("testdata/CFFTest.otf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted, err := trueTypeFont(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := fontTables(t, converted)
+	if findTable(tables, "CFF ") != nil || findTable(tables, "glyf") == nil || findTable(tables, "loca") == nil {
+		t.Fatal("la police convertie doit avoir glyf et loca à la place de CFF")
+	}
+
+	// Mêmes glyphes, même étendue des contours (à l'écart des quadratiques et à l'arrondi près)
+	cffFont, err := sfnt.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	glyfFont, err := sfnt.Parse(converted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cffFont.NumGlyphs() != glyfFont.NumGlyphs() {
+		t.Fatalf("%d glyphes, attendu %d", glyfFont.NumGlyphs(), cffFont.NumGlyphs())
+	}
+	var buffer sfnt.Buffer
+	ppem := fixed.Int26_6(cffFont.UnitsPerEm()) << 6
+	for i := 0; i < cffFont.NumGlyphs(); i++ {
+		var bounds [2][4]float64
+		for f, font := range []*sfnt.Font{cffFont, glyfFont} {
+			segments, err := font.LoadGlyph(&buffer, sfnt.GlyphIndex(i), ppem, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bounds[f] = segmentBounds(segments)
+		}
+		for k := range bounds[0] {
+			if math.Abs(bounds[0][k]-bounds[1][k]) > cffTolerance+1 {
+				t.Errorf("glyphe %d : étendue %v, attendu %v", i, bounds[1], bounds[0])
+				break
+			}
+		}
+	}
+}
+
+// segmentBounds retourne l'étendue (xMin, yMin, xMax, yMax) des courbes d'un glyphe, par échantillonnage
+func segmentBounds(segments sfnt.Segments) [4]float64 {
+	bounds := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	add := func(x, y float64) {
+		bounds = [4]float64{min(bounds[0], x), min(bounds[1], y), max(bounds[2], x), max(bounds[3], y)}
+	}
+	var current fixed.Point26_6
+	for _, segment := range segments {
+		points := []fixed.Point26_6{current}
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo, sfnt.SegmentOpLineTo:
+			points = segment.Args[:1]
+		case sfnt.SegmentOpQuadTo:
+			points = append(points, segment.Args[:2]...)
+		case sfnt.SegmentOpCubeTo:
+			points = append(points, segment.Args[:3]...)
+		}
+		// Point de la courbe de Bézier par l'algorithme de De Casteljau
+		for step := 0; step <= 64; step++ {
+			tt := float64(step) / 64
+			xs, ys := make([]float64, len(points)), make([]float64, len(points))
+			for j, p := range points {
+				xs[j], ys[j] = float64(p.X)/64, float64(p.Y)/64
+			}
+			for n := len(points) - 1; n > 0; n-- {
+				for j := 0; j < n; j++ {
+					xs[j], ys[j] = xs[j]+tt*(xs[j+1]-xs[j]), ys[j]+tt*(ys[j+1]-ys[j])
+				}
+			}
+			add(xs[0], ys[0])
+		}
+		current = points[len(points)-1]
+	}
+	return bounds
+}
+
+func TestCFFContours(t *testing.T) {
+	// at place un point en unités de dessin (axe Y vers le haut) dans le repère des segments
+	at := func(x, y int) fixed.Point26_6 { return fixed.Point26_6{X: fixed.I(x), Y: fixed.I(-y)} }
+	move := func(x, y int) sfnt.Segment {
+		return sfnt.Segment{Op: sfnt.SegmentOpMoveTo, Args: [3]fixed.Point26_6{at(x, y)}}
+	}
+	line := func(x, y int) sfnt.Segment {
+		return sfnt.Segment{Op: sfnt.SegmentOpLineTo, Args: [3]fixed.Point26_6{at(x, y)}}
+	}
+	cube := func(x1, y1, x2, y2, x3, y3 int) sfnt.Segment {
+		return sfnt.Segment{Op: sfnt.SegmentOpCubeTo, Args: [3]fixed.Point26_6{at(x1, y1), at(x2, y2), at(x3, y3)}}
+	}
+
+	tests := []struct {
+		name     string
+		segments sfnt.Segments
+		want     [][]glyphPoint
+	}{
+		{
+			"carré : sens inversé, dernier point répété retiré",
+			sfnt.Segments{move(0, 0), line(0, 100), line(100, 100), line(100, 0), line(0, 0)},
+			[][]glyphPoint{{{0, 0, true}, {100, 0, true}, {100, 100, true}, {0, 100, true}}},
+		},
+		{
+			"cubique de degré élevé : une seule quadratique",
+			sfnt.Segments{move(0, 0), cube(200, 200, 400, 200, 600, 0), line(0, 0)},
+			[][]glyphPoint{{{0, 0, true}, {600, 0, true}, {300, 300, false}}},
+		},
+		{
+			"deux contours",
+			sfnt.Segments{move(0, 0), line(10, 0), line(0, 10), move(20, 20), line(30, 20), line(20, 30)},
+			[][]glyphPoint{
+				{{0, 0, true}, {0, 10, true}, {10, 0, true}},
+				{{20, 20, true}, {20, 30, true}, {30, 20, true}},
+			},
+		},
+	}
+	for _, test := range tests {
+		got := cffContours(test.segments)
+		if len(got) != len(test.want) {
+			t.Errorf("%s : %v, attendu %v", test.name, got, test.want)
+			continue
+		}
+		for i := range got {
+			if !slices.Equal(got[i], test.want[i]) {
+				t.Errorf("%s : contour %d %v, attendu %v", test.name, i, got[i], test.want[i])
+			}
+		}
+	}
+
+	// Quart de cercle : découpé en plusieurs quadratiques dont les extrémités restent sur le cercle
+	contours := cffContours(sfnt.Segments{move(1000, 0), cube(1000, 552, 552, 1000, 0, 1000), line(0, 0)})
+	onCurve := 0
+	for _, point := range contours[0] {
+		if !point.onCurve || point == (glyphPoint{0, 0, true}) {
+			continue
+		}
+		onCurve++
+		if r := math.Hypot(float64(point.x), float64(point.y)); math.Abs(r-1000) > 1 {
+			t.Errorf("point (%d, %d) à %.1f du centre, attendu 1000", point.x, point.y, r)
+		}
+	}
+	if onCurve < 3 {
+		t.Errorf("%d point(s) sur la courbe, attendu un découpage en plusieurs quadratiques", onCurve)
+	}
+}
@@ -0,0 +1,91 @@
+package template
+
+import (
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+// --- Pack de polices intégré (build tag fontpack) ---
+//
+// Compilé avec -tags fontpack, le binaire (natif comme wasip1) embarque les polices
+// DejaVu Sans, Serif et Sans Mono : "default": "DejaVu" ou style.font "DejaVuSerif"
+// fonctionnent sans fournir de fichier TTF. Comme les polices déclarées, une variante du
+// pack n'est ajoutée au document qu'à sa première utilisation (setFont) ; une famille
+// déclarée dans fonts sous le même nom que le pack est prioritaire. Les variantes obliques
+// sont générées à partir des variantes droites par fontpack/oblique.go.
+
+// fontPack contient les fichiers du pack (répertoire fontpack/), nil sans le build tag
+var fontPack fs.FS
+
+// fontPackFamilies associe chaque famille du pack (en minuscules) aux fichiers de ses variantes
+var fontPackFamilies = map[string]map[string]string{
+	"dejavu":         dejaVuSans,
+	"dejavusans":     dejaVuSans,
+	"dejavuserif":    {"": "DejaVuSerif.ttf", "B": "DejaVuSerif-Bold.ttf", "I": "DejaVuSerif-Italic.ttf", "BI": "DejaVuSerif-BoldItalic.ttf"},
+	"dejavusansmono": {"": "DejaVuSansMono.ttf", "B": "DejaVuSansMono-Bold.ttf", "I": "DejaVuSansMono-Oblique.ttf", "BI": "DejaVuSansMono-BoldOblique.ttf"},
+}
+
+var dejaVuSans = map[string]string{"": "DejaVuSans.ttf", "B": "DejaVuSans-Bold.ttf", "I": "DejaVuSans-Oblique.ttf", "BI": "DejaVuSans-BoldOblique.ttf"}
+
+var (
+	fontPackFontsMu sync.Mutex
+	fontPackFonts   = map[string]*registeredFont{}
+)
+
+// fontPackFont retourne une variante du pack, enregistrée une fois par processus
+func fontPackFont(family, style string) (*registeredFont, bool) {
+	file, ok := fontPackFamilies[strings.ToLower(family)][style]
+	if !ok || fontPack == nil {
+		return nil, false
+	}
+
+	fontPackFontsMu.Lock()
+	font, ok := fontPackFonts[file]
+	fontPackFontsMu.Unlock()
+	if ok {
+		return font, true
+	}
+
+	data, err := fs.ReadFile(fontPack, "fontpack/"+file)
+	if err != nil {
+		return nil, false
+	}
+	if font, err = registerFont(data); err != nil {
+		return nil, false
+	}
+
+	fontPackFontsMu.Lock()
+	fontPackFonts[file] = font
+	fontPackFontsMu.Unlock()
+	return font, true
+}
+
+// setupFontPack déclare les familles du pack qui ne sont pas déclarées dans fonts ; leurs
+// variantes sont ajoutées au document à la demande par loadFont
+func (b *PDFBuilder) setupFontPack() {
+	declared := make(map[string]bool)
+	for name := range b.config.Fonts.Definitions {
+		declared[strings.ToLower(name)] = true
+	}
+
+	for family := range fontPackFamilies {
+		if b.utf8Fonts[family] || declared[family] {
+			continue
+		}
+		for _, style := range []string{"", "B", "I", "BI"} {
+			if font, ok := fontPackFont(family, style); ok {
+				b.declareFont(family, style, font)
+				declared := b.fontSources[family+style]
+				declared.pack = true
+				b.fontSources[family+style] = declared
+			}
+		}
+	}
+}
+
+// isFontPackFamily indique si une famille du document est celle du pack (et non une famille
+// déclarée dans fonts sous le même nom)
+func (b *PDFBuilder) isFontPackFamily(family string) bool {
+	return b.fontSources[strings.ToLower(family)].pack
+}
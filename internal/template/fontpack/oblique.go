@@ -0,0 +1,779 @@
+//go:build ignore
+
+// oblique génère les variantes obliques (italiques) du pack à partir des variantes normale et
+// grasse : les contours sont inclinés de 10° (l'inclinaison des contours des DejaVu Oblique) et les instructions de
+// hinting, propres aux contours droits, sont supprimées.
+//
+// Utilisation, depuis ce répertoire : go run oblique.go
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// italicAngle est l'inclinaison des variantes générées, en degrés (sens horaire)
+const italicAngle = 10.0
+
+var variants = []struct {
+	source, target, subfamily string
+}{
+	{"DejaVuSans.ttf", "DejaVuSans-Oblique.ttf", "Oblique"},
+	{"DejaVuSans-Bold.ttf", "DejaVuSans-BoldOblique.ttf", "Bold Oblique"},
+	{"DejaVuSerif.ttf", "DejaVuSerif-Italic.ttf", "Italic"},
+	{"DejaVuSerif-Bold.ttf", "DejaVuSerif-BoldItalic.ttf", "Bold Italic"},
+	{"DejaVuSansMono.ttf", "DejaVuSansMono-Oblique.ttf", "Oblique"},
+	{"DejaVuSansMono-Bold.ttf", "DejaVuSansMono-BoldOblique.ttf", "Bold Oblique"},
+}
+
+func main() {
+	for _, v := range variants {
+		data, err := os.ReadFile(v.source)
+		if err == nil {
+			data, err = oblique(data, v.subfamily)
+		}
+		if err == nil {
+			err = os.WriteFile(v.target, data, 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s : %v\n", v.source, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s -> %s (%d octets)\n", v.source, v.target, len(data))
+	}
+}
+
+// hintingTables sont supprimées : elles décrivent le hinting des contours droits
+var hintingTables = map[string]bool{"cvt ": true, "fpgm": true, "prep": true, "hdmx": true, "LTSH": true, "VDMX": true}
+
+// oblique retourne la police inclinée, avec la sous-famille donnée ("Oblique", "Bold Italic"...)
+func oblique(data []byte, subfamily string) ([]byte, error) {
+	tables, err := readTables(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "post", "OS/2", "name"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("table %s absente", tag)
+		}
+	}
+
+	head, hhea, maxp := tables["head"], tables["hhea"], tables["maxp"]
+	numGlyphs := int(u16(maxp, 4))
+	offsets, err := glyphOffsets(tables["loca"], numGlyphs, u16(head, 50) == 1, len(tables["glyf"]))
+	if err != nil {
+		return nil, err
+	}
+
+	// Chaque glyphe est incliné autour du milieu de sa hauteur, comme dans les DejaVu Oblique : il
+	// garde sa place dans la chasse. Les composites dont les composants sont simplement décalés
+	// le restent, décalages corrigés ; les autres sont aplatis.
+	slant := math.Tan(italicAngle * math.Pi / 180)
+	g := glyphSet{glyf: tables["glyf"], offsets: offsets}
+	outlines := make([][][]point, numGlyphs)
+	centers := make([]float64, numGlyphs)
+	for id := range outlines {
+		if outlines[id], err = g.outline(id, 0); err != nil {
+			return nil, fmt.Errorf("glyphe %d : %v", id, err)
+		}
+		centers[id] = verticalCenter(outlines[id])
+	}
+
+	var glyf []byte
+	loca := make([]byte, 0, 4*(numGlyphs+1))
+	boxes := make([]bbox, numGlyphs)
+	maxPoints, maxContours := 0, 0
+	for id, contours := range outlines {
+		for _, contour := range contours {
+			for p := range contour {
+				contour[p].x += (contour[p].y - centers[id]) * slant
+			}
+		}
+		encoded, box, points := encodeGlyph(contours)
+		if components, ok := g.components(id); ok {
+			for i, c := range components {
+				components[i].dx += int(math.Round((float64(c.dy) + centers[c.glyph] - centers[id]) * slant))
+			}
+			encoded = encodeComposite(components, box)
+		} else {
+			maxPoints, maxContours = max(maxPoints, points), max(maxContours, len(contours))
+		}
+		boxes[id] = box
+
+		loca = binary.BigEndian.AppendUint32(loca, uint32(len(glyf)))
+		glyf = append(glyf, encoded...)
+	}
+	loca = binary.BigEndian.AppendUint32(loca, uint32(len(glyf)))
+	tables["glyf"], tables["loca"] = glyf, loca
+
+	// Métriques horizontales : chasses inchangées, approches gauches égales aux nouveaux xMin
+	numHMetrics := int(u16(hhea, 34))
+	hmtx := append([]byte(nil), tables["hmtx"]...)
+	fontBox := bbox{empty: true}
+	minLSB, minRSB, maxExtent := math.MaxInt16, math.MaxInt16, math.MinInt16
+	for id := 0; id < numGlyphs; id++ {
+		advance := int(u16(hmtx, 4*min(id, numHMetrics-1)))
+		lsbOffset := 4*numHMetrics + 2*(id-numHMetrics)
+		if id < numHMetrics {
+			lsbOffset = 4*id + 2
+		}
+		box := boxes[id]
+		if box.empty {
+			continue
+		}
+		binary.BigEndian.PutUint16(hmtx[lsbOffset:], uint16(int16(box.xMin)))
+		minLSB = min(minLSB, box.xMin)
+		minRSB = min(minRSB, advance-box.xMax)
+		maxExtent = max(maxExtent, box.xMax)
+		fontBox = fontBox.union(box)
+	}
+	tables["hmtx"] = hmtx
+
+	head = append([]byte(nil), head...)
+	putI16(head, 36, fontBox.xMin)
+	putI16(head, 38, fontBox.yMin)
+	putI16(head, 40, fontBox.xMax)
+	putI16(head, 42, fontBox.yMax)
+	binary.BigEndian.PutUint16(head[44:], u16(head, 44)|2) // macStyle : italique
+	binary.BigEndian.PutUint16(head[50:], 1)               // loca au format long
+	tables["head"] = head
+
+	hhea = append([]byte(nil), hhea...)
+	putI16(hhea, 12, minLSB)
+	putI16(hhea, 14, minRSB)
+	putI16(hhea, 16, maxExtent)
+	putI16(hhea, 18, 100) // pente du curseur
+	putI16(hhea, 20, int(math.Round(100*slant)))
+	tables["hhea"] = hhea
+
+	maxp = append([]byte(nil), maxp...)
+	if len(maxp) >= 32 {
+		putU16(maxp, 6, max(int(u16(maxp, 6)), maxPoints))
+		putU16(maxp, 8, max(int(u16(maxp, 8)), maxContours))
+		putU16(maxp, 26, 0) // plus d'instructions
+		putU16(maxp, 28, 0)
+		putU16(maxp, 30, 0)
+	}
+	tables["maxp"] = maxp
+
+	post := append([]byte(nil), tables["post"]...)
+	italic := int32(-italicAngle * 65536) // format 16.16
+	binary.BigEndian.PutUint32(post[4:], uint32(italic))
+	tables["post"] = post
+
+	os2 := append([]byte(nil), tables["OS/2"]...)
+	fsSelection := u16(os2, 62)&^0x40 | 0x01 // italique, plus « regular »
+	binary.BigEndian.PutUint16(os2[62:], fsSelection)
+	tables["OS/2"] = os2
+
+	if tables["name"], err = renameFont(tables["name"], subfamily); err != nil {
+		return nil, err
+	}
+	for tag := range hintingTables {
+		delete(tables, tag)
+	}
+	return writeFont(tables), nil
+}
+
+// --- Lecture et écriture du conteneur ---
+
+func u16(b []byte, off int) uint16     { return binary.BigEndian.Uint16(b[off:]) }
+func i16(b []byte, off int) int        { return int(int16(binary.BigEndian.Uint16(b[off:]))) }
+func putU16(b []byte, off int, v int)  { binary.BigEndian.PutUint16(b[off:], uint16(v)) }
+func putI16(b []byte, off int, v int)  { binary.BigEndian.PutUint16(b[off:], uint16(int16(v))) }
+func appendI16(b []byte, v int) []byte { return binary.BigEndian.AppendUint16(b, uint16(int16(v))) }
+
+func readTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 || binary.BigEndian.Uint32(data) != 0x00010000 {
+		return nil, errors.New("police TrueType attendue")
+	}
+	count := int(u16(data, 4))
+	tables := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, errors.New("répertoire des tables tronqué")
+		}
+		tag := string(data[record : record+4])
+		offset, length := binary.BigEndian.Uint32(data[record+8:]), binary.BigEndian.Uint32(data[record+12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("table %s hors du fichier", tag)
+		}
+		tables[tag] = data[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// writeFont écrit les tables triées par étiquette, alignées sur 4 octets, sommes de contrôle comprises
+func writeFont(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	count := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= count {
+		entrySelector++
+	}
+	searchRange := 16 << entrySelector
+
+	out := binary.BigEndian.AppendUint32(nil, 0x00010000)
+	out = binary.BigEndian.AppendUint16(out, uint16(count))
+	out = binary.BigEndian.AppendUint16(out, uint16(searchRange))
+	out = binary.BigEndian.AppendUint16(out, uint16(entrySelector))
+	out = binary.BigEndian.AppendUint16(out, uint16(count*16-searchRange))
+
+	offset := 12 + 16*count
+	headOffset := 0
+	var body []byte
+	for _, tag := range tags {
+		data := tables[tag]
+		if tag == "head" {
+			data = append([]byte(nil), data...)
+			binary.BigEndian.PutUint32(data[8:], 0)
+			headOffset = offset + len(body)
+		}
+		out = append(out, tag...)
+		out = binary.BigEndian.AppendUint32(out, checksum(data))
+		out = binary.BigEndian.AppendUint32(out, uint32(offset+len(body)))
+		out = binary.BigEndian.AppendUint32(out, uint32(len(data)))
+		body = append(body, data...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+	out = append(out, body...)
+	binary.BigEndian.PutUint32(out[headOffset+8:], 0xB1B0AFBA-checksum(out))
+	return out
+}
+
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// --- Glyphes ---
+
+type point struct {
+	x, y float64
+	on   bool
+}
+
+type bbox struct {
+	xMin, yMin, xMax, yMax int
+	empty                  bool
+}
+
+func (a bbox) union(b bbox) bbox {
+	if a.empty {
+		return b
+	}
+	return bbox{xMin: min(a.xMin, b.xMin), yMin: min(a.yMin, b.yMin), xMax: max(a.xMax, b.xMax), yMax: max(a.yMax, b.yMax)}
+}
+
+type glyphSet struct {
+	glyf    []byte
+	offsets []int
+}
+
+func glyphOffsets(loca []byte, numGlyphs int, long bool, glyfLen int) ([]int, error) {
+	offsets := make([]int, numGlyphs+1)
+	for i := range offsets {
+		switch {
+		case long && 4*i+4 <= len(loca):
+			offsets[i] = int(binary.BigEndian.Uint32(loca[4*i:]))
+		case !long && 2*i+2 <= len(loca):
+			offsets[i] = 2 * int(u16(loca, 2*i))
+		default:
+			return nil, errors.New("table loca tronquée")
+		}
+		if offsets[i] > glyfLen || (i > 0 && offsets[i] < offsets[i-1]) {
+			return nil, errors.New("table loca invalide")
+		}
+	}
+	return offsets, nil
+}
+
+// Drapeaux des glyphes simples et des composants
+const (
+	onCurve          = 0x01
+	xShort           = 0x02
+	yShort           = 0x04
+	repeatFlag       = 0x08
+	xSame            = 0x10
+	ySame            = 0x20
+	argsAreWords     = 0x0001
+	argsAreXY        = 0x0002
+	haveScale        = 0x0008
+	moreComps        = 0x0020
+	haveXYScale      = 0x0040
+	have2x2          = 0x0080
+	haveInstructions = 0x0100
+	scaledOffset     = 0x0800
+)
+
+// outline retourne les contours d'un glyphe, composants aplatis
+func (g glyphSet) outline(id, depth int) ([][]point, error) {
+	if depth > 8 {
+		return nil, errors.New("composites trop imbriqués")
+	}
+	if id < 0 || id+1 >= len(g.offsets) {
+		return nil, fmt.Errorf("composant %d hors de la police", id)
+	}
+	data := g.glyf[g.offsets[id]:g.offsets[id+1]]
+	if len(data) < 10 {
+		return nil, nil
+	}
+	if contours := i16(data, 0); contours >= 0 {
+		return simpleOutline(data, contours)
+	}
+	return g.compositeOutline(data[10:], depth)
+}
+
+func simpleOutline(data []byte, count int) ([][]point, error) {
+	pos := 10
+	ends := make([]int, count)
+	for c := range ends {
+		if pos+2 > len(data) {
+			return nil, errors.New("glyphe tronqué")
+		}
+		ends[c] = int(u16(data, pos))
+		pos += 2
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	if pos+2 > len(data) {
+		return nil, errors.New("glyphe tronqué")
+	}
+	pos += 2 + int(u16(data, pos)) // instructions ignorées
+	points := ends[count-1] + 1
+
+	flags := make([]byte, 0, points)
+	for len(flags) < points {
+		if pos >= len(data) {
+			return nil, errors.New("drapeaux tronqués")
+		}
+		flag := data[pos]
+		pos++
+		flags = append(flags, flag)
+		if flag&repeatFlag != 0 {
+			if pos >= len(data) {
+				return nil, errors.New("drapeaux tronqués")
+			}
+			for n := data[pos]; n > 0 && len(flags) < points; n-- {
+				flags = append(flags, flag)
+			}
+			pos++
+		}
+	}
+
+	readCoords := func(short, same byte) ([]int, error) {
+		coords := make([]int, points)
+		v := 0
+		for i, flag := range flags {
+			switch {
+			case flag&short != 0:
+				if pos >= len(data) {
+					return nil, errors.New("coordonnées tronquées")
+				}
+				d := int(data[pos])
+				pos++
+				if flag&same == 0 {
+					d = -d
+				}
+				v += d
+			case flag&same == 0:
+				if pos+2 > len(data) {
+					return nil, errors.New("coordonnées tronquées")
+				}
+				v += i16(data, pos)
+				pos += 2
+			}
+			coords[i] = v
+		}
+		return coords, nil
+	}
+	xs, err := readCoords(xShort, xSame)
+	if err != nil {
+		return nil, err
+	}
+	ys, err := readCoords(yShort, ySame)
+	if err != nil {
+		return nil, err
+	}
+
+	contours := make([][]point, count)
+	start := 0
+	for c, end := range ends {
+		if end < start || end >= points {
+			return nil, errors.New("fins de contours invalides")
+		}
+		for p := start; p <= end; p++ {
+			contours[c] = append(contours[c], point{float64(xs[p]), float64(ys[p]), flags[p]&onCurve != 0})
+		}
+		start = end + 1
+	}
+	return contours, nil
+}
+
+func (g glyphSet) compositeOutline(data []byte, depth int) ([][]point, error) {
+	var contours [][]point
+	pos := 0
+	for {
+		if pos+4 > len(data) {
+			return nil, errors.New("composant tronqué")
+		}
+		flags, component := u16(data, pos), int(u16(data, pos+2))
+		pos += 4
+
+		var arg1, arg2 int
+		if flags&argsAreWords != 0 {
+			if pos+4 > len(data) {
+				return nil, errors.New("composant tronqué")
+			}
+			arg1, arg2 = i16(data, pos), i16(data, pos+2)
+			if flags&argsAreXY == 0 {
+				arg1, arg2 = int(u16(data, pos)), int(u16(data, pos+2))
+			}
+			pos += 4
+		} else {
+			if pos+2 > len(data) {
+				return nil, errors.New("composant tronqué")
+			}
+			arg1, arg2 = int(int8(data[pos])), int(int8(data[pos+1]))
+			if flags&argsAreXY == 0 {
+				arg1, arg2 = int(data[pos]), int(data[pos+1])
+			}
+			pos += 2
+		}
+
+		// Matrice [a c ; b d] au format F2Dot14
+		a, b, c, d := 1.0, 0.0, 0.0, 1.0
+		f2dot14 := func(off int) float64 { return float64(int16(u16(data, off))) / 16384 }
+		switch {
+		case flags&haveScale != 0 && pos+2 <= len(data):
+			a = f2dot14(pos)
+			d = a
+			pos += 2
+		case flags&haveXYScale != 0 && pos+4 <= len(data):
+			a, d = f2dot14(pos), f2dot14(pos+2)
+			pos += 4
+		case flags&have2x2 != 0 && pos+8 <= len(data):
+			a, b, c, d = f2dot14(pos), f2dot14(pos+2), f2dot14(pos+4), f2dot14(pos+6)
+			pos += 8
+		case flags&(haveScale|haveXYScale|have2x2) != 0:
+			return nil, errors.New("transformation tronquée")
+		}
+
+		parts, err := g.outline(component, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			for p, pt := range part {
+				part[p].x, part[p].y = a*pt.x+c*pt.y, b*pt.x+d*pt.y
+			}
+		}
+
+		dx, dy := float64(arg1), float64(arg2)
+		if flags&argsAreXY == 0 {
+			// Points appariés : le point arg2 du composant est placé sur le point arg1 du glyphe
+			parent, child := pointAt(contours, arg1), pointAt(parts, arg2)
+			if parent == nil || child == nil {
+				return nil, errors.New("point d'appariement absent")
+			}
+			dx, dy = parent.x-child.x, parent.y-child.y
+		} else if flags&scaledOffset != 0 {
+			dx, dy = a*dx+c*dy, b*dx+d*dy
+		}
+		for _, part := range parts {
+			for p := range part {
+				part[p].x += dx
+				part[p].y += dy
+			}
+		}
+		contours = append(contours, parts...)
+
+		if flags&moreComps == 0 {
+			return contours, nil
+		}
+	}
+}
+
+func pointAt(contours [][]point, n int) *point {
+	for _, contour := range contours {
+		if n < len(contour) {
+			return &contour[n]
+		}
+		n -= len(contour)
+	}
+	return nil
+}
+
+// verticalCenter retourne le milieu de la hauteur des contours, 0 sans contour
+func verticalCenter(contours [][]point) float64 {
+	yMin, yMax := math.Inf(1), math.Inf(-1)
+	for _, contour := range contours {
+		for _, p := range contour {
+			yMin, yMax = min(yMin, p.y), max(yMax, p.y)
+		}
+	}
+	if yMin > yMax {
+		return 0
+	}
+	return (yMin + yMax) / 2
+}
+
+// component est un composant simplement décalé d'un glyphe composite
+type component struct {
+	flags  uint16
+	glyph  int
+	dx, dy int
+}
+
+// components retourne les composants d'un glyphe composite, faux si ce n'est pas un composite
+// ou si l'un de ses composants est transformé ou placé par appariement de points
+func (g glyphSet) components(id int) ([]component, bool) {
+	data := g.glyf[g.offsets[id]:g.offsets[id+1]]
+	if len(data) < 10 || i16(data, 0) >= 0 {
+		return nil, false
+	}
+	var components []component
+	for pos := 10; ; {
+		if pos+4 > len(data) {
+			return nil, false
+		}
+		flags := u16(data, pos)
+		if flags&argsAreXY == 0 || flags&(haveScale|haveXYScale|have2x2) != 0 {
+			return nil, false
+		}
+		c := component{flags: flags, glyph: int(u16(data, pos+2))}
+		pos += 4
+		if flags&argsAreWords != 0 {
+			if pos+4 > len(data) {
+				return nil, false
+			}
+			c.dx, c.dy = i16(data, pos), i16(data, pos+2)
+			pos += 4
+		} else {
+			if pos+2 > len(data) {
+				return nil, false
+			}
+			c.dx, c.dy = int(int8(data[pos])), int(int8(data[pos+1]))
+			pos += 2
+		}
+		components = append(components, c)
+		if flags&moreComps == 0 {
+			return components, true
+		}
+	}
+}
+
+// encodeComposite écrit un glyphe composite sans instructions, aligné sur 4 octets
+func encodeComposite(components []component, box bbox) []byte {
+	out := appendI16(nil, -1)
+	out = appendI16(out, box.xMin)
+	out = appendI16(out, box.yMin)
+	out = appendI16(out, box.xMax)
+	out = appendI16(out, box.yMax)
+	for _, c := range components {
+		flags := c.flags &^ (argsAreWords | haveInstructions)
+		words := c.dx < -128 || c.dx > 127 || c.dy < -128 || c.dy > 127
+		if words {
+			flags |= argsAreWords
+		}
+		out = binary.BigEndian.AppendUint16(out, flags)
+		out = binary.BigEndian.AppendUint16(out, uint16(c.glyph))
+		if words {
+			out = appendI16(out, c.dx)
+			out = appendI16(out, c.dy)
+		} else {
+			out = append(out, byte(int8(c.dx)), byte(int8(c.dy)))
+		}
+	}
+	for len(out)%4 != 0 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+// encodeGlyph écrit un glyphe simple sans instructions, aligné sur 4 octets ; un glyphe sans
+// contour est vide
+func encodeGlyph(contours [][]point) ([]byte, bbox, int) {
+	var xs, ys []int
+	var on []bool
+	var ends []int
+	for _, contour := range contours {
+		if len(contour) == 0 {
+			continue
+		}
+		for _, p := range contour {
+			xs = append(xs, int(math.Round(p.x)))
+			ys = append(ys, int(math.Round(p.y)))
+			on = append(on, p.on)
+		}
+		ends = append(ends, len(xs)-1)
+	}
+	if len(xs) == 0 {
+		return nil, bbox{empty: true}, 0
+	}
+
+	box := bbox{xMin: xs[0], yMin: ys[0], xMax: xs[0], yMax: ys[0]}
+	for i := range xs {
+		box.xMin, box.xMax = min(box.xMin, xs[i]), max(box.xMax, xs[i])
+		box.yMin, box.yMax = min(box.yMin, ys[i]), max(box.yMax, ys[i])
+	}
+
+	out := appendI16(nil, len(ends))
+	out = appendI16(out, box.xMin)
+	out = appendI16(out, box.yMin)
+	out = appendI16(out, box.xMax)
+	out = appendI16(out, box.yMax)
+	for _, end := range ends {
+		out = binary.BigEndian.AppendUint16(out, uint16(end))
+	}
+	out = binary.BigEndian.AppendUint16(out, 0) // pas d'instructions
+
+	var flags []byte
+	var xBytes, yBytes []byte
+	encode := func(delta int, short, same byte, coords *[]byte) byte {
+		switch {
+		case delta == 0:
+			return same
+		case delta > -256 && delta < 256:
+			if delta > 0 {
+				*coords = append(*coords, byte(delta))
+				return short | same
+			}
+			*coords = append(*coords, byte(-delta))
+			return short
+		}
+		*coords = appendI16(*coords, delta)
+		return 0
+	}
+	prevX, prevY := 0, 0
+	for i := range xs {
+		flag := encode(xs[i]-prevX, xShort, xSame, &xBytes) | encode(ys[i]-prevY, yShort, ySame, &yBytes)
+		if on[i] {
+			flag |= onCurve
+		}
+		flags = append(flags, flag)
+		prevX, prevY = xs[i], ys[i]
+	}
+
+	// Drapeaux identiques consécutifs regroupés
+	for i := 0; i < len(flags); {
+		run := 1
+		for i+run < len(flags) && flags[i+run] == flags[i] && run < 256 {
+			run++
+		}
+		if run > 1 {
+			out = append(out, flags[i]|repeatFlag, byte(run-1))
+		} else {
+			out = append(out, flags[i])
+		}
+		i += run
+	}
+	out = append(out, xBytes...)
+	out = append(out, yBytes...)
+	for len(out)%4 != 0 {
+		out = append(out, 0)
+	}
+	return out, box, len(xs)
+}
+
+// --- Noms ---
+
+// renameFont remplace la sous-famille et les noms complets (identifiants 2, 3, 4, 6 et 17)
+func renameFont(name []byte, subfamily string) ([]byte, error) {
+	if len(name) < 6 || u16(name, 0) != 0 {
+		return nil, errors.New("table name au format 0 attendue")
+	}
+	count, storage := int(u16(name, 2)), int(u16(name, 4))
+	if 6+12*count > len(name) {
+		return nil, errors.New("table name tronquée")
+	}
+
+	type record struct {
+		platform, encoding, language, id uint16
+		text                             []byte
+	}
+	records := make([]record, count)
+	family := ""
+	for i := range records {
+		off := 6 + 12*i
+		length, start := int(u16(name, off+8)), storage+int(u16(name, off+10))
+		if start+length > len(name) {
+			return nil, errors.New("nom hors de la table")
+		}
+		records[i] = record{u16(name, off), u16(name, off+2), u16(name, off+4), u16(name, off+6), name[start : start+length]}
+		if records[i].id == 1 && records[i].platform == 3 {
+			family = decodeUTF16(records[i].text)
+		}
+	}
+	if family == "" {
+		return nil, errors.New("nom de famille absent")
+	}
+
+	full := family + " " + subfamily
+	names := map[uint16]string{
+		2:  subfamily,
+		3:  full,
+		4:  full,
+		6:  strings.ReplaceAll(family, " ", "") + "-" + strings.ReplaceAll(subfamily, " ", ""),
+		17: subfamily,
+	}
+
+	out := binary.BigEndian.AppendUint16(nil, 0)
+	out = binary.BigEndian.AppendUint16(out, uint16(count))
+	out = binary.BigEndian.AppendUint16(out, uint16(6+12*count))
+	var strs []byte
+	for _, r := range records {
+		text := r.text
+		if s, ok := names[r.id]; ok {
+			if r.platform == 1 {
+				text = []byte(s)
+			} else {
+				text = encodeUTF16(s)
+			}
+		}
+		out = binary.BigEndian.AppendUint16(out, r.platform)
+		out = binary.BigEndian.AppendUint16(out, r.encoding)
+		out = binary.BigEndian.AppendUint16(out, r.language)
+		out = binary.BigEndian.AppendUint16(out, r.id)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(text)))
+		out = binary.BigEndian.AppendUint16(out, uint16(len(strs)))
+		strs = append(strs, text...)
+	}
+	return append(out, strs...), nil
+}
+
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = u16(b, 2*i)
+	}
+	return string(utf16.Decode(units))
+}
+
+func encodeUTF16(s string) []byte {
+	var out []byte
+	for _, unit := range utf16.Encode([]rune(s)) {
+		out = binary.BigEndian.AppendUint16(out, unit)
+	}
+	return out
+}
@@ -0,0 +1,12 @@
+//go:build fontpack
+
+package template
+
+import "embed"
+
+//go:embed fontpack/*.ttf
+var fontPackFiles embed.FS
+
+func init() {
+	fontPack = fontPackFiles
+}
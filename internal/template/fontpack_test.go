@@ -0,0 +1,101 @@
+package template
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// withFontPack active le pack (répertoire fontpack/) sans le build tag
+func withFontPack(t *testing.T) {
+	t.Helper()
+	saved := fontPack
+	fontPack = os.DirFS(".")
+	t.Cleanup(func() { fontPack = saved })
+}
+
+func TestFontPackStyles(t *testing.T) {
+	withFontPack(t)
+
+	tests := []struct {
+		font  string
+		style Style
+	}{
+		{"DejaVu", Style{}},
+		{"DejaVuSerif", Style{Bold: true}},
+		{"DejaVuSerif", Style{Italic: true}},
+		{"DejaVuSansMono", Style{Italic: true}},
+		{"DejaVu", Style{Bold: true, Italic: true}},
+	}
+	for _, tt := range tests {
+		style := tt.style
+		style.Font = tt.font
+		template := Template{Elements: []Element{{Type: "text", Content: "Zürich", Style: &style}}}
+
+		if _, err := NewPDFBuilder(template).Build(); err != nil {
+			t.Errorf("%s %+v : erreur %v", tt.font, tt.style, err)
+		}
+	}
+}
+
+func TestFontPackItalic(t *testing.T) {
+	withFontPack(t)
+
+	// L'italique des éléments riches et des lignes de report (gras italique par défaut) utilise
+	// les variantes obliques du pack
+	var items []interface{}
+	for i := 1; i <= 80; i++ {
+		items = append(items, map[string]interface{}{"label": fmt.Sprintf("Ligne %d", i), "amount": float64(i)})
+	}
+	template := Template{
+		Fonts: FontConfig{Default: "DejaVu"},
+		Elements: []Element{
+			{Type: "markdown", Content: "Un *mot* en italique\n\n> Citation"},
+			{Type: "html", Content: "<p>Un <i>mot</i> en italique</p>"},
+			{
+				Type:       "table",
+				DataSource: "items",
+				Columns: []TableColumn{
+					{Header: "Libellé", Width: 100, Field: "label"},
+					{Header: "Montant", Width: 40, Field: "amount", Format: "%.2f"},
+				},
+				CarryForward: &TableCarryForward{Field: "amount"},
+			},
+		},
+	}
+
+	builder := NewPDFBuilder(template)
+	builder.vars = map[string]interface{}{"items": items}
+	if _, err := builder.Build(); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"dejavuI", "dejavuBI"} {
+		if !builder.fontsAdded[key] {
+			t.Errorf("variante %s non ajoutée au document", key)
+		}
+	}
+}
+
+func TestFontPackOverriddenFamily(t *testing.T) {
+	withFontPack(t)
+
+	// Une famille déclarée dans fonts sous le nom du pack le remplace, italique compris
+	data, err := os.ReadFile("fontpack/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	template := Template{
+		Fonts:    FontConfig{Embedded: map[string]EmbeddedFontData{"DejaVu": {Regular: encoded, Italic: encoded}}},
+		Elements: []Element{{Type: "text", Content: "Zürich", Style: &Style{Font: "DejaVu", Italic: true}}},
+	}
+
+	builder := NewPDFBuilder(template)
+	if _, err := builder.Build(); err != nil {
+		t.Fatal(err)
+	}
+	if builder.isFontPackFamily("DejaVu") {
+		t.Error("famille déclarée prise pour celle du pack")
+	}
+}
@@ -0,0 +1,351 @@
+package template
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash/maphash"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Registre des polices (partagé par tous les documents du processus) ---
+//
+// En mode serveur ou par lots, les mêmes polices base64 reviennent à chaque document.
+// Le registre garde chaque fichier de police décodé (et converti en TrueType) une seule
+// fois, indexé par l'empreinte SHA-256 de son contenu, avec sa couverture de caractères
+// (table cmap). Un texte base64 déjà vu n'est pas décodé à nouveau (il est reconnu par une
+// empreinte, le texte lui-même n'est pas gardé), un fichier (paths) inchangé n'est pas relu.
+//
+// La taille du registre est bornée (SetFontRegistryLimit) : au-delà, les polices les moins
+// récemment utilisées sont retirées. PreloadFonts remplit le registre au démarrage avec des
+// polices qui ne sont jamais retirées ; ResetFontRegistry le vide.
+//
+// L'analyse TrueType de gofpdf n'est pas partagée : gofpdf n'a pas d'API pour réutiliser une
+// police analysée dans un autre document. Dans un document, une variante de police déclarée
+// n'est donc ajoutée à gofpdf (qui analyse le fichier et en inclura un sous-ensemble dans le
+// PDF) qu'à sa première utilisation par setFont.
+
+// defaultFontRegistryLimit est la taille maximale par défaut des polices du registre, en octets
+const defaultFontRegistryLimit = 128 << 20
+
+// fontHash est l'empreinte SHA-256 d'un contenu
+type fontHash [sha256.Size]byte
+
+// encodedKey reconnaît un texte base64 sans le garder en mémoire : empreinte et longueur
+type encodedKey struct {
+	hash   uint64
+	length int
+}
+
+// registeredFont est un fichier de police décodé et converti en TrueType, partagé entre
+// documents (lecture seule : gofpdf reçoit une copie, voir loadFont)
+type registeredFont struct {
+	data         []byte // Police TrueType
+	size         int    // Taille du fichier fourni (WOFF, OpenType... avant conversion)
+	coverageOnce sync.Once
+	coverage     glyphCoverage
+
+	// Place dans le registre, protégée par fontRegistry
+	hash    fontHash
+	element *list.Element // nil une fois la police retirée du registre
+	pinned  bool          // Préchargée (PreloadFonts) : jamais retirée
+	encoded []encodedKey  // Textes base64 qui désignent cette police
+	paths   []string      // Fichiers qui désignent cette police
+}
+
+// glyphs retourne la couverture de caractères de la police, lue une fois à la première demande
+func (f *registeredFont) glyphs() glyphCoverage {
+	f.coverageOnce.Do(func() {
+		f.coverage = parseCoverage(f.data)
+	})
+	return f.coverage
+}
+
+var fontRegistry = struct {
+	sync.Mutex
+	fonts   map[fontHash]*registeredFont   // Fichiers par empreinte du contenu décodé
+	encoded map[encodedKey]*registeredFont // Fichiers par empreinte du texte base64 (évite de décoder à nouveau)
+	files   map[string]registeredFile      // Fichiers par chemin (évite de relire un fichier inchangé)
+	seed    maphash.Seed
+	recent  *list.List // Polices, de la plus récemment utilisée à la plus ancienne
+	size    int64      // Taille totale des polices du registre
+	limit   int64
+}{
+	fonts:   map[fontHash]*registeredFont{},
+	encoded: map[encodedKey]*registeredFont{},
+	files:   map[string]registeredFile{},
+	seed:    maphash.MakeSeed(),
+	recent:  list.New(),
+	limit:   defaultFontRegistryLimit,
+}
+
+// registeredFile est un fichier de police lu depuis le disque, avec sa taille et sa date
+type registeredFile struct {
+	size    int64
+	modTime time.Time
+	font    *registeredFont
+}
+
+// SetFontRegistryLimit fixe la taille maximale des polices gardées par le registre, en octets
+// (128 Mo par défaut) ; les polices préchargées par PreloadFonts ne sont jamais retirées
+func SetFontRegistryLimit(bytes int64) {
+	fontRegistry.Lock()
+	defer fontRegistry.Unlock()
+	fontRegistry.limit = bytes
+	evictFonts()
+}
+
+// useFont marque une police comme la plus récemment utilisée (fontRegistry verrouillé)
+func useFont(font *registeredFont) {
+	if font.element != nil {
+		fontRegistry.recent.MoveToFront(font.element)
+	}
+}
+
+// evictFonts retire les polices les moins récemment utilisées tant que le registre dépasse
+// sa taille maximale (fontRegistry verrouillé)
+func evictFonts() {
+	element := fontRegistry.recent.Back()
+	for element != nil && fontRegistry.size > fontRegistry.limit {
+		font := element.Value.(*registeredFont)
+		element = element.Prev()
+		if font.pinned {
+			continue
+		}
+
+		delete(fontRegistry.fonts, font.hash)
+		for _, key := range font.encoded {
+			if fontRegistry.encoded[key] == font {
+				delete(fontRegistry.encoded, key)
+			}
+		}
+		for _, path := range font.paths {
+			if fontRegistry.files[path].font == font {
+				delete(fontRegistry.files, path)
+			}
+		}
+		fontRegistry.recent.Remove(font.element)
+		fontRegistry.size -= int64(len(font.data))
+		font.element, font.encoded, font.paths = nil, nil, nil
+	}
+}
+
+// registerFont enregistre un fichier de police, converti en TrueType s'il est dans un autre format
+// (voir trueTypeFont) ; un contenu déjà enregistré est partagé
+func registerFont(data []byte) (*registeredFont, error) {
+	hash := fontHash(sha256.Sum256(data))
+
+	fontRegistry.Lock()
+	font, ok := fontRegistry.fonts[hash]
+	if ok {
+		useFont(font)
+	}
+	fontRegistry.Unlock()
+	if ok {
+		return font, nil
+	}
+
+	converted, err := trueTypeFont(data)
+	if err != nil {
+		return nil, err
+	}
+
+	fontRegistry.Lock()
+	defer fontRegistry.Unlock()
+	if font, ok := fontRegistry.fonts[hash]; ok {
+		useFont(font)
+		return font, nil
+	}
+	font = &registeredFont{data: converted, size: len(data), hash: hash}
+	font.element = fontRegistry.recent.PushFront(font)
+	fontRegistry.fonts[hash] = font
+	fontRegistry.size += int64(len(converted))
+	evictFonts()
+	return font, nil
+}
+
+// registerBase64Font décode et enregistre un fichier de police en base64, une seule fois par texte
+func registerBase64Font(encoded string) (*registeredFont, error) {
+	key := encodedKey{maphash.String(fontRegistry.seed, encoded), len(encoded)}
+
+	fontRegistry.Lock()
+	font, ok := fontRegistry.encoded[key]
+	if ok {
+		useFont(font)
+	}
+	fontRegistry.Unlock()
+	if ok {
+		return font, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if font, err = registerFont(data); err != nil {
+		return nil, err
+	}
+
+	fontRegistry.Lock()
+	if font.element != nil {
+		fontRegistry.encoded[key] = font
+		font.encoded = append(font.encoded, key)
+	}
+	fontRegistry.Unlock()
+	return font, nil
+}
+
+// registerFontFile lit et enregistre un fichier de police ; un fichier de même taille et de
+// même date qu'à la lecture précédente n'est pas relu
+func registerFontFile(path string) (*registeredFont, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	fontRegistry.Lock()
+	file, ok := fontRegistry.files[path]
+	ok = ok && file.size == info.Size() && file.modTime.Equal(info.ModTime())
+	if ok {
+		useFont(file.font)
+	}
+	fontRegistry.Unlock()
+	if ok {
+		return file.font, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	font, err := registerFont(data)
+	if err != nil {
+		return nil, err
+	}
+
+	fontRegistry.Lock()
+	if font.element != nil {
+		fontRegistry.files[path] = registeredFile{info.Size(), info.ModTime(), font}
+		font.paths = append(font.paths, path)
+	}
+	fontRegistry.Unlock()
+	return font, nil
+}
+
+// pinFont garde une police préchargée dans le registre
+func pinFont(font *registeredFont) {
+	fontRegistry.Lock()
+	font.pinned = font.element != nil
+	fontRegistry.Unlock()
+}
+
+// PreloadFonts décode et enregistre une fois pour toutes les polices d'une configuration
+// (embedded, base64Data, paths, definitions), par exemple au démarrage d'un serveur ; les
+// documents qui déclarent ensuite les mêmes polices réutilisent les fichiers décodés, qui ne
+// sont jamais retirés du registre (sauf par ResetFontRegistry)
+func PreloadFonts(fonts FontConfig) error {
+	// Polices TrueType : fichier décodé et couverture de caractères
+	var encoded []string
+	for _, variants := range fonts.Embedded {
+		encoded = append(encoded, variants.Regular, variants.Bold, variants.Italic, variants.BoldItalic)
+	}
+	for _, data := range fonts.Base64Data {
+		encoded = append(encoded, data)
+	}
+	for _, data := range encoded {
+		if data == "" {
+			continue
+		}
+		font, err := registerBase64Font(data)
+		if err != nil {
+			return err
+		}
+		pinFont(font)
+		font.glyphs()
+	}
+	for _, path := range fonts.Paths {
+		font, err := registerFontFile(path)
+		if err != nil {
+			return err
+		}
+		pinFont(font)
+		font.glyphs()
+	}
+
+	// Polices makefont : fichiers .json et .z décodés
+	for _, definition := range fonts.Definitions {
+		for _, files := range []FontDefinitionFiles{definition.Regular, definition.Bold, definition.Italic, definition.BoldItalic} {
+			if files.JSON == "" {
+				continue
+			}
+			for _, data := range []string{files.JSON, files.Z} {
+				font, err := registerBase64Font(data)
+				if err != nil {
+					return err
+				}
+				pinFont(font)
+			}
+		}
+	}
+	return nil
+}
+
+// ResetFontRegistry vide le registre des polices, préchargées comprises (les documents en cours
+// gardent leurs fichiers)
+func ResetFontRegistry() {
+	fontRegistry.Lock()
+	defer fontRegistry.Unlock()
+	for element := fontRegistry.recent.Front(); element != nil; element = element.Next() {
+		font := element.Value.(*registeredFont)
+		font.element, font.encoded, font.paths = nil, nil, nil
+	}
+	fontRegistry.fonts = map[fontHash]*registeredFont{}
+	fontRegistry.encoded = map[encodedKey]*registeredFont{}
+	fontRegistry.files = map[string]registeredFile{}
+	fontRegistry.recent = list.New()
+	fontRegistry.size = 0
+}
+
+// declaredFont est une variante de police UTF-8 déclarée pour un document
+type declaredFont struct {
+	family string // Nom de famille tel que déclaré
+	font   *registeredFont
+	pack   bool // Variante du pack intégré (voir setupFontPack)
+}
+
+// declareFont rend disponible une variante de police UTF-8 du registre ; elle sera ajoutée au
+// document par loadFont à sa première utilisation. La première déclaration d'une variante l'emporte.
+func (b *PDFBuilder) declareFont(family, style string, font *registeredFont) {
+	key := strings.ToLower(family)
+	if _, ok := b.fontSources[key+style]; ok {
+		return
+	}
+	b.fontSources[key+style] = declaredFont{family: family, font: font}
+	b.utf8Fonts[key] = true
+	if style == "" {
+		b.coverage[key] = font.glyphs()
+	}
+}
+
+// loadFont ajoute au document la variante d'une police déclarée à sa première utilisation
+func (b *PDFBuilder) loadFont(family, style string) {
+	// Le soulignement n'est pas une variante de police
+	variant := ""
+	if strings.Contains(strings.ToUpper(style), "B") {
+		variant += "B"
+	}
+	if strings.Contains(strings.ToUpper(style), "I") {
+		variant += "I"
+	}
+
+	key := strings.ToLower(family) + variant
+	declared, ok := b.fontSources[key]
+	if !ok || b.fontsAdded[key] {
+		return
+	}
+	b.fontsAdded[key] = true
+
+	b.addUTF8Font(declared.family, variant, declared.font)
+}
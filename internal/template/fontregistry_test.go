@@ -0,0 +1,151 @@
+package template
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// withFontRegistry vide le registre et fixe sa taille maximale pour un test
+func withFontRegistry(t *testing.T, limit int64) {
+	t.Helper()
+	ResetFontRegistry()
+	SetFontRegistryLimit(limit)
+	t.Cleanup(func() {
+		ResetFontRegistry()
+		SetFontRegistryLimit(defaultFontRegistryLimit)
+	})
+}
+
+// fakeFont est un fichier de police de taille donnée (un contenu inconnu est gardé tel quel)
+func fakeFont(name byte, size int) []byte {
+	return bytes.Repeat([]byte{name}, size)
+}
+
+func TestFontRegistryBase64(t *testing.T) {
+	withFontRegistry(t, defaultFontRegistryLimit)
+
+	a := base64.StdEncoding.EncodeToString(fakeFont('a', 100))
+	b := base64.StdEncoding.EncodeToString(fakeFont('b', 100))
+	first, err := registerBase64Font(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := registerBase64Font(a)
+	other, _ := registerBase64Font(b)
+	if first != again {
+		t.Error("le même texte base64 donne deux polices")
+	}
+	if first == other {
+		t.Error("deux textes base64 différents donnent la même police")
+	}
+
+	// Le même contenu par un fichier est partagé avec le texte base64
+	path := filepath.Join(t.TempDir(), "a.ttf")
+	if err := os.WriteFile(path, fakeFont('a', 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if file, _ := registerFontFile(path); file != first {
+		t.Error("le fichier et le texte base64 de même contenu donnent deux polices")
+	}
+
+	if _, err := registerBase64Font("pas du base64 !"); err == nil {
+		t.Error("texte base64 invalide accepté")
+	}
+}
+
+func TestFontRegistryEviction(t *testing.T) {
+	withFontRegistry(t, 250)
+
+	encode := func(name byte) string { return base64.StdEncoding.EncodeToString(fakeFont(name, 100)) }
+	a, _ := registerBase64Font(encode('a'))
+	b, _ := registerBase64Font(encode('b'))
+	registerBase64Font(encode('a')) // a devient la plus récemment utilisée
+	c, _ := registerBase64Font(encode('c'))
+
+	tests := []struct {
+		name       string
+		font       *registeredFont
+		registered bool
+	}{
+		{"a (réutilisée)", a, true},
+		{"b (la plus ancienne)", b, false},
+		{"c (la plus récente)", c, true},
+	}
+	fontRegistry.Lock()
+	for _, test := range tests {
+		if registered := test.font.element != nil; registered != test.registered {
+			t.Errorf("police %s : dans le registre %v, attendu %v", test.name, registered, test.registered)
+		}
+	}
+	if fontRegistry.size != 200 || len(fontRegistry.fonts) != 2 || len(fontRegistry.encoded) != 2 {
+		t.Errorf("registre de %d octets, %d police(s), %d texte(s), attendu 200, 2 et 2",
+			fontRegistry.size, len(fontRegistry.fonts), len(fontRegistry.encoded))
+	}
+	fontRegistry.Unlock()
+
+	// Une police retirée est décodée à nouveau
+	if again, _ := registerBase64Font(encode('b')); again == b || again.element == nil {
+		t.Error("la police retirée n'est pas enregistrée à nouveau")
+	}
+}
+
+func TestFontRegistryPreloadedFontsStay(t *testing.T) {
+	withFontRegistry(t, 150)
+
+	preloaded := base64.StdEncoding.EncodeToString(fakeFont('p', 100))
+	if err := PreloadFonts(FontConfig{Base64Data: map[string]string{"Préchargée": preloaded}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []byte("xyz") {
+		registerFont(fakeFont(name, 100))
+	}
+
+	font, _ := registerBase64Font(preloaded)
+	fontRegistry.Lock()
+	defer fontRegistry.Unlock()
+	if font.element == nil || !font.pinned {
+		t.Error("police préchargée retirée du registre")
+	}
+	if fontRegistry.size > 200 {
+		t.Errorf("registre de %d octets, attendu au plus la police préchargée et une autre", fontRegistry.size)
+	}
+}
+
+func TestFontRegistryConcurrent(t *testing.T) {
+	withFontRegistry(t, 300)
+
+	var encoded []string
+	for _, name := range []byte("abcdef") {
+		encoded = append(encoded, base64.StdEncoding.EncodeToString(fakeFont(name, 100)))
+	}
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				data := encoded[(i+j)%len(encoded)]
+				font, err := registerBase64Font(data)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if want, _ := base64.StdEncoding.DecodeString(data); !bytes.Equal(font.data, want) {
+					t.Error("police différente du texte base64")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	fontRegistry.Lock()
+	defer fontRegistry.Unlock()
+	if fontRegistry.size > 300 || int(fontRegistry.size) != 100*fontRegistry.recent.Len() {
+		t.Errorf("registre de %d octets pour %d police(s), limite 300", fontRegistry.size, fontRegistry.recent.Len())
+	}
+}
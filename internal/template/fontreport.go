@@ -0,0 +1,122 @@
+package template
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// --- Taille des polices incluses dans le PDF ---
+//
+// gofpdf n'inclut des polices UTF-8 qu'un sous-ensemble : les glyphes des caractères
+// écrits dans le document (plus les caractères ASCII de base), compressé. Les polices
+// 8 bits générées par makefont (fonts.definitions) sont incluses entières. Le rapport
+// relit le PDF produit pour donner, police par police, la taille réellement incluse et
+// celle qu'aurait la police entière compressée de la même façon.
+
+// EmbeddedFont décrit une police incluse dans un PDF
+type EmbeddedFont struct {
+	Name         string `json:"name"`         // Nom de la police dans le PDF
+	Family       string `json:"family"`       // Famille déclarée dans fonts (vide si inconnue)
+	Style        string `json:"style"`        // Variante : "", "B", "I" ou "BI"
+	Subset       bool   `json:"subset"`       // Sous-ensemble des caractères utilisés (polices UTF-8)
+	FileSize     int    `json:"fileSize"`     // Taille du fichier de police fourni, en octets
+	FullSize     int    `json:"fullSize"`     // Taille de la police entière une fois compressée, en octets
+	EmbeddedSize int    `json:"embeddedSize"` // Taille réellement incluse dans le PDF, en octets
+}
+
+// fontFile est un fichier de police ajouté au document, retenu pour le rapport de taille
+type fontFile struct {
+	family string
+	style  string
+	data   []byte // Police TrueType (polices UTF-8), nil pour les polices makefont incluses entières
+	size   int    // Taille du fichier fourni
+}
+
+var (
+	pdfObjectPattern     = regexp.MustCompile(`(?s)\n(\d+) 0 obj\n<<(.*?)>>\n(?:stream|endobj)`)
+	fontDescriptorFields = regexp.MustCompile(`(?s)/FontName /(\S+?)[\s/].*/FontFile2? (\d+) 0 R`)
+	streamLengthFields   = regexp.MustCompile(`/Length (\d+)`)
+)
+
+// addUTF8Font ajoute une variante de police du registre au document et retient son fichier
+func (b *PDFBuilder) addUTF8Font(family, style string, font *registeredFont) {
+	// gofpdf écrit dans le fichier en calculant le sous-ensemble : chaque document a sa copie
+	b.pdf.AddUTF8FontFromBytes(family, style, append([]byte(nil), font.data...))
+	b.fontFiles["utf8"+strings.ToLower(family)+style] = fontFile{family: family, style: style, data: font.data, size: font.size}
+}
+
+// FontReport liste les polices incluses dans un PDF produit par ce builder (voir Build),
+// de la plus lourde à la plus légère
+func (b *PDFBuilder) FontReport(pdf []byte) []EmbeddedFont {
+	// Taille des flux par numéro d'objet, puis descripteurs de police
+	lengths := make(map[string]int)
+	var descriptors [][]string
+	for _, object := range pdfObjectPattern.FindAllSubmatch(pdf, -1) {
+		dictionary := string(object[2])
+		if fields := fontDescriptorFields.FindStringSubmatch(dictionary); fields != nil {
+			descriptors = append(descriptors, fields[1:])
+		} else if length := streamLengthFields.FindStringSubmatch(dictionary); length != nil {
+			lengths[string(object[1])], _ = strconv.Atoi(length[1])
+		}
+	}
+
+	var fonts []EmbeddedFont
+	for _, descriptor := range descriptors {
+		font := EmbeddedFont{Name: descriptor[0], EmbeddedSize: lengths[descriptor[1]]}
+		if file, ok := b.fontFiles[font.Name]; ok {
+			font.Family, font.Style = file.family, file.style
+			font.FileSize, font.FullSize = file.size, font.EmbeddedSize
+			if file.data != nil {
+				font.Subset = true
+				font.FullSize = compressedSize(file.data)
+			}
+		}
+		fonts = append(fonts, font)
+	}
+
+	sort.SliceStable(fonts, func(i, j int) bool {
+		return fonts[i].EmbeddedSize > fonts[j].EmbeddedSize
+	})
+	return fonts
+}
+
+// compressedSize retourne la taille d'un fichier compressé comme gofpdf compresse les polices
+func compressedSize(data []byte) int {
+	var buf bytes.Buffer
+	writer, _ := zlib.NewWriterLevel(&buf, zlib.BestSpeed)
+	writer.Write(data)
+	writer.Close()
+	return buf.Len()
+}
+
+// WriteFontReport écrit un rapport de polices sous forme de tableau texte, avec le total
+func WriteFontReport(w io.Writer, fonts []EmbeddedFont) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "Police\tStyle\tSous-ensemble\tFichier\tEntière\tIncluse")
+
+	fullTotal, embeddedTotal := 0, 0
+	for _, font := range fonts {
+		family, style, subset := font.Family, font.Style, "non"
+		if family == "" {
+			family = font.Name
+		}
+		if style == "" {
+			style = "-"
+		}
+		if font.Subset {
+			subset = "oui"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%d\t%d\n", family, style, subset, font.FileSize, font.FullSize, font.EmbeddedSize)
+		fullTotal += font.FullSize
+		embeddedTotal += font.EmbeddedSize
+	}
+	fmt.Fprintf(table, "Total\t\t\t\t%d\t%d\n", fullTotal, embeddedTotal)
+	return table.Flush()
+}
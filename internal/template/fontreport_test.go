@@ -0,0 +1,113 @@
+package template
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// invoiceText est le texte du test de sous-ensemble (make test-fonts)
+const invoiceText = "Facture n° 2024-001 — Zürich, 1 250,00 €"
+
+func TestFontReportSubset(t *testing.T) {
+	template := Template{
+		Fonts:    FontConfig{Default: "DejaVu", Paths: map[string]string{"DejaVu": "fontpack/DejaVuSans.ttf"}},
+		Elements: []Element{{Type: "text", Content: invoiceText}},
+	}
+	pdf, fonts, err := GeneratePDFWithFontReport(template, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fonts) != 1 {
+		t.Fatalf("%d police(s) dans le rapport, attendu 1 : %+v", len(fonts), fonts)
+	}
+
+	font := fonts[0]
+	info, err := os.Stat("fontpack/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if font.Family != "DejaVu" || !font.Subset || font.FileSize != int(info.Size()) {
+		t.Errorf("police %+v, attendu DejaVu en sous-ensemble, fichier de %d octets", font, info.Size())
+	}
+	if font.EmbeddedSize <= 0 || font.EmbeddedSize*10 >= font.FullSize || font.FullSize >= font.FileSize {
+		t.Errorf("tailles incohérentes : incluse %d, entière %d, fichier %d", font.EmbeddedSize, font.FullSize, font.FileSize)
+	}
+	if !bytes.Contains(pdf, []byte("/Length "+strconv.Itoa(font.EmbeddedSize))) {
+		t.Errorf("aucun flux de %d octets dans le PDF", font.EmbeddedSize)
+	}
+}
+
+func TestFontReportDefinition(t *testing.T) {
+	// Police 8 bits générée par makefont : incluse entière
+	files := fontDefinitionFiles(t, "cp1252")
+
+	template := Template{
+		Fonts: FontConfig{
+			Default:     "DejaVu",
+			Paths:       map[string]string{"DejaVu": "fontpack/DejaVuSans.ttf"},
+			Definitions: map[string]FontDefinition{"DejaVu8": {Regular: files}},
+		},
+		Elements: []Element{
+			{Type: "text", Content: invoiceText},
+			{Type: "text", Content: invoiceText, Style: &Style{Font: "DejaVu8"}},
+		},
+	}
+	_, fonts, err := GeneratePDFWithFontReport(template, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fonts) != 2 {
+		t.Fatalf("%d police(s) dans le rapport, attendu 2 : %+v", len(fonts), fonts)
+	}
+
+	// La police entière vient en premier (tri par taille incluse)
+	full, subset := fonts[0], fonts[1]
+	if full.Family != "DejaVu8" || full.Subset || full.FullSize != full.EmbeddedSize {
+		t.Errorf("police makefont %+v, attendu DejaVu8 incluse entière", full)
+	}
+	if subset.Family != "DejaVu" || !subset.Subset {
+		t.Errorf("police UTF-8 %+v, attendu DejaVu en sous-ensemble", subset)
+	}
+
+	// Le total du tableau est la somme des polices
+	var report strings.Builder
+	if err := WriteFontReport(&report, fonts); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	if len(lines) != len(fonts)+2 {
+		t.Fatalf("rapport de %d lignes :\n%s", len(lines), report.String())
+	}
+	fullTotal, embeddedTotal := 0, 0
+	for i, font := range fonts {
+		fields := strings.Fields(lines[i+1])
+		if got := fields[len(fields)-3:]; strings.Join(got, " ") !=
+			strconv.Itoa(font.FileSize)+" "+strconv.Itoa(font.FullSize)+" "+strconv.Itoa(font.EmbeddedSize) {
+			t.Errorf("ligne %q, attendu les tailles de %+v", lines[i+1], font)
+		}
+		fullTotal += font.FullSize
+		embeddedTotal += font.EmbeddedSize
+	}
+	total := strings.Fields(lines[len(lines)-1])
+	if strings.Join(total, " ") != "Total "+strconv.Itoa(fullTotal)+" "+strconv.Itoa(embeddedTotal) {
+		t.Errorf("ligne de total %q, attendu %d et %d", lines[len(lines)-1], fullTotal, embeddedTotal)
+	}
+}
+
+func TestFontDefinitionInvalidJSON(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	template := Template{
+		Fonts: FontConfig{Definitions: map[string]FontDefinition{
+			"Cassée": {Regular: FontDefinitionFiles{JSON: encode(`{"Tp": "TrueType", "Name": `), Z: encode("z")}},
+		}},
+		Elements: []Element{{Type: "text", Content: "Texte"}},
+	}
+	_, err := GeneratePDF(template)
+	if err == nil || !strings.Contains(err.Error(), "Cassée") {
+		t.Errorf("erreur %v, attendu une erreur sur la définition Cassée", err)
+	}
+}
@@ -0,0 +1,246 @@
+package template
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// --- Grilles : colonnes, largeurs, espacements et alignement vertical ---
+
+// minGridColumnWidth est la largeur minimale (mm) d'une colonne "fr" quand les largeurs fixes
+// dépassent l'espace disponible
+const minGridColumnWidth = 10.0
+
+// gridCell est un enfant de grille placé sur une ou plusieurs colonnes
+type gridCell struct {
+	element Element
+	column  int // première colonne occupée
+	span    int // nombre de colonnes occupées
+}
+
+// gridLayout décrit le placement des colonnes et des enfants d'une grille pour une largeur donnée
+type gridLayout struct {
+	widths  []float64 // largeur de chaque colonne
+	offsets []float64 // position de chaque colonne depuis le bord gauche de la grille
+	gap     float64
+	rowGap  float64
+	rows    [][]gridCell
+}
+
+// newGridLayout calcule la disposition d'une grille dans la largeur disponible
+func newGridLayout(element Element, width float64) (gridLayout, bool) {
+	columns := element.GridColumns
+	if columns <= 0 {
+		columns = len(element.ColumnWidths)
+	}
+	if columns <= 0 || len(element.Children) == 0 {
+		return gridLayout{}, false
+	}
+	rows := gridRows(element.Children, columns)
+	if len(rows) == 0 {
+		return gridLayout{}, false
+	}
+
+	layout := gridLayout{gap: 2.0}
+	if element.Gap != nil {
+		layout.gap = *element.Gap
+	}
+	layout.rowGap = layout.gap
+	if element.RowGap != nil {
+		layout.rowGap = *element.RowGap
+	}
+
+	layout.widths = resolveColumnWidths(element.ColumnWidths, columns, width, layout.gap)
+	layout.offsets = make([]float64, columns)
+	for col := 1; col < columns; col++ {
+		layout.offsets[col] = layout.offsets[col-1] + layout.widths[col-1] + layout.gap
+	}
+	layout.rows = rows
+
+	return layout, true
+}
+
+// cellBox retourne la position (depuis le bord gauche de la grille) et la largeur d'un enfant
+func (g gridLayout) cellBox(cell gridCell) (offset, width float64) {
+	for col := cell.column; col < cell.column+cell.span; col++ {
+		width += g.widths[col]
+	}
+	width += g.gap * float64(cell.span-1)
+	return g.offsets[cell.column], width
+}
+
+func (b *PDFBuilder) renderGrid(element Element) {
+	// Disposition calculée dans la zone courante (permet les grilles imbriquées)
+	layout, ok := newGridLayout(element, b.area.width)
+	if !ok {
+		return
+	}
+	areaX := b.area.x
+
+	defaultVAlign := ""
+	if element.Style != nil {
+		defaultVAlign = element.Style.VAlign
+	}
+
+	// Traiter les éléments par ligne complète
+	for _, row := range layout.rows {
+		startY := b.pdf.GetY()
+
+		// Mesurer la ligne sans la dessiner
+		heights, maxHeight := b.measureGridRow(layout, row)
+
+		// Rendre tous les éléments de la ligne, alignés verticalement
+		for i, cell := range row {
+			offset, width := layout.cellBox(cell)
+			x := areaX + offset
+
+			valign := defaultVAlign
+			if cell.element.Style != nil && cell.element.Style.VAlign != "" {
+				valign = cell.element.Style.VAlign
+			}
+
+			y := startY
+			switch valign {
+			case "middle":
+				y += (maxHeight - heights[i]) / 2
+			case "bottom":
+				y += maxHeight - heights[i]
+			}
+
+			b.pdf.SetXY(x, y)
+			b.renderElementInWidth(cell.element, x, width)
+		}
+
+		// Avancer à la ligne suivante
+		b.pdf.SetXY(areaX, startY+maxHeight+layout.rowGap)
+	}
+
+	for _, child := range element.Children {
+		if child.Position != nil {
+			b.renderPositioned(child)
+		}
+	}
+}
+
+// measureGridRow mesure chaque enfant d'une ligne de grille et la hauteur de la ligne
+func (b *PDFBuilder) measureGridRow(layout gridLayout, row []gridCell) (heights []float64, maxHeight float64) {
+	heights = make([]float64, len(row))
+	for i, cell := range row {
+		_, width := layout.cellBox(cell)
+		heights[i] = b.measureElementInWidth(cell.element, width)
+		if heights[i] > maxHeight {
+			maxHeight = heights[i]
+		}
+	}
+	return heights, maxHeight
+}
+
+// gridRows répartit les enfants en lignes : un enfant dont le colSpan ne tient plus
+// dans la ligne courante commence une nouvelle ligne
+func gridRows(children []Element, columns int) [][]gridCell {
+	var rows [][]gridCell
+	var current []gridCell
+	used := 0
+
+	for _, child := range children {
+		// Les enfants positionnés sont dessinés hors de la grille
+		if child.Position != nil {
+			continue
+		}
+
+		span := child.ColSpan
+		if span <= 0 {
+			span = 1
+		}
+		if span > columns {
+			span = columns
+		}
+
+		if used+span > columns {
+			rows = append(rows, current)
+			current, used = nil, 0
+		}
+
+		current = append(current, gridCell{element: child, column: used, span: span})
+		used += span
+	}
+
+	if len(current) > 0 {
+		rows = append(rows, current)
+	}
+	return rows
+}
+
+// resolveColumnWidths calcule la largeur de chaque colonne. Les valeurs absolues (mm) et les
+// pourcentages (de la largeur disponible) sont servis d'abord ; l'espace restant, gaps déduits,
+// est partagé entre les fractions ("1fr"). Une colonne non précisée vaut "1fr".
+// Si les largeurs fixes dépassent l'espace disponible, elles sont réduites proportionnellement
+// pour laisser au moins minGridColumnWidth à chaque fraction.
+func resolveColumnWidths(specs []interface{}, columns int, available, gap float64) []float64 {
+	widths := make([]float64, columns)
+	fractions := make([]float64, columns)
+
+	space := math.Max(available-gap*float64(columns-1), 0)
+	fixed, totalFr, frColumns := 0.0, 0.0, 0
+
+	for col := 0; col < columns; col++ {
+		var spec interface{} = "1fr"
+		if col < len(specs) && specs[col] != nil {
+			spec = specs[col]
+		}
+
+		switch v := spec.(type) {
+		case float64:
+			widths[col] = v
+		case string:
+			value := strings.TrimSpace(v)
+			switch {
+			case strings.HasSuffix(value, "fr"):
+				fr, err := strconv.ParseFloat(strings.TrimSuffix(value, "fr"), 64)
+				if err != nil || fr <= 0 {
+					fr = 1
+				}
+				fractions[col] = fr
+			case strings.HasSuffix(value, "%"):
+				if pct, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64); err == nil {
+					widths[col] = available * pct / 100
+				}
+			default:
+				if mm, err := strconv.ParseFloat(strings.TrimSuffix(value, "mm"), 64); err == nil {
+					widths[col] = mm
+				} else {
+					fractions[col] = 1
+				}
+			}
+		default:
+			fractions[col] = 1
+		}
+
+		fixed += widths[col]
+		totalFr += fractions[col]
+		if fractions[col] > 0 {
+			frColumns++
+		}
+	}
+
+	// Largeurs fixes réduites si elles ne laissent pas la largeur minimale aux fractions
+	reserved := float64(frColumns) * math.Min(minGridColumnWidth, space/float64(columns))
+	if fixed > space-reserved && fixed > 0 {
+		scale := (space - reserved) / fixed
+		for col := range widths {
+			widths[col] *= scale
+		}
+		fixed = space - reserved
+	}
+
+	if totalFr > 0 {
+		for col := range widths {
+			if fractions[col] > 0 {
+				widths[col] = (space - fixed) * fractions[col] / totalFr
+			}
+		}
+	}
+
+	return widths
+}
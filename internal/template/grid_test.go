@@ -0,0 +1,80 @@
+package template
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResolveColumnWidths(t *testing.T) {
+	tests := []struct {
+		name           string
+		specs          []interface{}
+		columns        int
+		available, gap float64
+		want           []float64
+	}{
+		{"mm", []interface{}{40.0, "60mm"}, 2, 180, 2, []float64{40, 60}},
+		{"pourcentages", []interface{}{"25%", "50%"}, 2, 200, 0, []float64{50, 100}},
+		{"fractions", []interface{}{"1fr", "3fr"}, 2, 182, 2, []float64{45, 135}},
+		{"mm et fraction", []interface{}{30.0, "1fr"}, 2, 180, 2, []float64{30, 148}},
+		{"colonnes non précisées", []interface{}{40.0}, 3, 184, 2, []float64{40, 70, 70}},
+		{"fraction invalide", []interface{}{"xfr", "1fr"}, 2, 100, 0, []float64{50, 50}},
+		{"largeurs fixes trop grandes", []interface{}{120.0, "40%", "1fr"}, 3, 200, 0, []float64{114, 76, 10}},
+		{"largeurs fixes trop grandes sans fraction", []interface{}{150.0, 50.0}, 2, 100, 0, []float64{75, 25}},
+		{"zone plus étroite que le minimum", []interface{}{30.0, "1fr"}, 2, 12, 2, []float64{5, 5}},
+		{"gaps plus larges que la zone", []interface{}{"1fr", "1fr"}, 2, 1, 2, []float64{0, 0}},
+	}
+	for _, test := range tests {
+		got := resolveColumnWidths(test.specs, test.columns, test.available, test.gap)
+		if len(got) != len(test.want) {
+			t.Fatalf("%s : %d colonne(s), attendu %d", test.name, len(got), len(test.want))
+		}
+		for col := range got {
+			if math.Abs(got[col]-test.want[col]) > 1e-9 {
+				t.Errorf("%s : largeurs %v, attendu %v", test.name, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestGridRows(t *testing.T) {
+	child := func(span int) Element { return Element{Type: "text", ColSpan: span} }
+	tests := []struct {
+		name     string
+		children []Element
+		columns  int
+		want     [][][2]int // (colonne, span) de chaque enfant, par ligne
+	}{
+		{"une ligne", []Element{child(0), child(1), child(1)}, 3, [][][2]int{{{0, 1}, {1, 1}, {2, 1}}}},
+		{"retour à la ligne", []Element{child(2), child(2), child(1)}, 3, [][][2]int{{{0, 2}}, {{0, 2}, {2, 1}}}},
+		{"colSpan limité au nombre de colonnes", []Element{child(5), child(1)}, 2, [][][2]int{{{0, 2}}, {{0, 1}}}},
+		{"enfant positionné ignoré", []Element{child(1), {Type: "text", Position: &Position{}}, child(1)}, 2, [][][2]int{{{0, 1}, {1, 1}}}},
+	}
+	for _, test := range tests {
+		rows := gridRows(test.children, test.columns)
+		var got [][][2]int
+		for _, row := range rows {
+			var cells [][2]int
+			for _, cell := range row {
+				cells = append(cells, [2]int{cell.column, cell.span})
+			}
+			got = append(got, cells)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s : %v, attendu %v", test.name, got, test.want)
+			continue
+		}
+		for r := range got {
+			if len(got[r]) != len(test.want[r]) {
+				t.Errorf("%s : %v, attendu %v", test.name, got, test.want)
+				break
+			}
+			for c := range got[r] {
+				if got[r][c] != test.want[r][c] {
+					t.Errorf("%s : %v, attendu %v", test.name, got, test.want)
+				}
+			}
+		}
+	}
+}
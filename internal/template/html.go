@@ -0,0 +1,212 @@
+package template
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// --- Fragments HTML ---
+//
+// Un élément html est découpé par le tokenizer de gofpdf (HTMLBasicTokenize) puis
+// converti en blocs de texte riche, comme un élément markdown, ce qui permet de le
+// mesurer et de l'adapter à la largeur d'une colonne de grille. Balises prises en
+// charge : b/strong, i/em, u, a, code, br, p, div, h1-h6, ul, ol, li, blockquote et
+// hr. Les autres balises sont ignorées (leur texte est conservé), le contenu des
+// balises script et style est supprimé.
+
+var htmlSpaceRe = regexp.MustCompile(`\s+`)
+
+// htmlList est une liste ouverte (ul ou ol) avec son compteur
+type htmlList struct {
+	ordered bool
+	counter int
+}
+
+// htmlConverter accumule les blocs produits par la lecture d'un fragment HTML
+type htmlConverter struct {
+	blocks   blockStyles
+	elements []Element
+
+	spans []TextSpan
+	block func(spans []TextSpan) Element // constructeur du bloc en cours (paragraphe par défaut)
+
+	bold, italic, underline, code int
+	links                         []string
+	lists                         []htmlList
+}
+
+// htmlElements convertit le contenu d'un élément html en éléments
+func htmlElements(element Element) []Element {
+	c := &htmlConverter{blocks: newBlockStyles(element.Style)}
+
+	segments := gofpdf.HTMLBasicTokenize(textContent(element))
+	skip := "" // balise dont le contenu est supprimé (script, style)
+
+	for _, segment := range segments {
+		tag := strings.Trim(segment.Str, "/ ")
+
+		if skip != "" {
+			if segment.Cat == 'C' && tag == skip {
+				skip = ""
+			}
+			continue
+		}
+
+		switch segment.Cat {
+		case 'T':
+			c.text(segment.Str)
+		case 'O':
+			if tag == "script" || tag == "style" {
+				skip = tag
+				continue
+			}
+			c.open(tag, segment.Attr)
+		case 'C':
+			c.close(tag)
+		}
+	}
+
+	c.flush()
+	c.blocks.end(c.elements)
+	return c.elements
+}
+
+func (c *htmlConverter) open(tag string, attr map[string]string) {
+	switch tag {
+	case "b", "strong":
+		c.bold++
+	case "i", "em":
+		c.italic++
+	case "u":
+		c.underline++
+	case "code":
+		c.code++
+	case "a":
+		c.links = append(c.links, attr["href"])
+	case "br":
+		c.spans = append(c.spans, TextSpan{Text: "\n"})
+	case "p", "div":
+		c.flush()
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		c.flush()
+		level, _ := strconv.Atoi(tag[1:])
+		c.block = func(spans []TextSpan) Element {
+			return c.blocks.heading(level, spans, len(c.elements) == 0)
+		}
+	case "blockquote":
+		c.flush()
+		c.block = c.blocks.quote
+	case "ul", "ol":
+		c.flush()
+		c.lists = append(c.lists, htmlList{ordered: tag == "ol"})
+	case "li":
+		c.flush()
+		if len(c.lists) == 0 {
+			c.lists = append(c.lists, htmlList{})
+		}
+		list := &c.lists[len(c.lists)-1]
+		list.counter++
+		marker := "•"
+		if list.ordered {
+			marker = strconv.Itoa(list.counter) + "."
+		}
+		level := len(c.lists) - 1
+		c.block = func(spans []TextSpan) Element {
+			return c.blocks.listItem(marker, level, spans)
+		}
+	case "hr":
+		c.flush()
+		c.elements = append(c.elements, Element{Type: "line", Style: &Style{
+			Color: "#999999", Margin: []float64{c.blocks.gap / 2, 0, c.blocks.gap, 0},
+		}})
+	}
+}
+
+func (c *htmlConverter) close(tag string) {
+	switch tag {
+	case "b", "strong":
+		if c.bold > 0 {
+			c.bold--
+		}
+	case "i", "em":
+		if c.italic > 0 {
+			c.italic--
+		}
+	case "u":
+		if c.underline > 0 {
+			c.underline--
+		}
+	case "code":
+		if c.code > 0 {
+			c.code--
+		}
+	case "a":
+		if len(c.links) > 0 {
+			c.links = c.links[:len(c.links)-1]
+		}
+	case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "li":
+		c.flush()
+	case "ul", "ol":
+		c.flush()
+		if len(c.lists) > 0 {
+			c.lists = c.lists[:len(c.lists)-1]
+		}
+		c.blocks.endList(c.elements)
+	}
+}
+
+// text ajoute un texte avec le style en ligne courant ; les blancs sont regroupés comme en HTML
+func (c *htmlConverter) text(raw string) {
+	text := htmlSpaceRe.ReplaceAllString(html.UnescapeString(raw), " ")
+	if text == "" || (text == " " && len(c.spans) == 0) {
+		return
+	}
+
+	var style *Style
+	if c.bold > 0 || c.italic > 0 || c.underline > 0 || c.code > 0 {
+		style = &Style{Bold: c.bold > 0, Italic: c.italic > 0, Underline: c.underline > 0}
+		if c.code > 0 {
+			style.Font = markdownCodeFont
+		}
+	}
+
+	span := TextSpan{Text: text, Style: style}
+	if len(c.links) > 0 {
+		span.Link = c.links[len(c.links)-1]
+	}
+	c.spans = append(c.spans, span)
+}
+
+// flush termine le bloc en cours ; un bloc sans texte visible est abandonné
+func (c *htmlConverter) flush() {
+	spans, block := c.spans, c.block
+	c.spans, c.block = nil, nil
+
+	visible := false
+	for _, span := range spans {
+		if strings.TrimSpace(span.Text) != "" {
+			visible = true
+			break
+		}
+	}
+	if !visible {
+		return
+	}
+
+	// Pas d'espace ni de retour à la ligne en début de bloc
+	for len(spans) > 0 && strings.TrimSpace(spans[0].Text) == "" {
+		spans = spans[1:]
+	}
+	spans[0].Text = strings.TrimLeft(spans[0].Text, " ")
+
+	if block == nil {
+		block = func(spans []TextSpan) Element {
+			return c.blocks.paragraph(spans, c.blocks.withSize(1), 0)
+		}
+	}
+	c.elements = append(c.elements, block(spans))
+}
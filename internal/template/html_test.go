@@ -0,0 +1,90 @@
+package template
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// spansSummary écrit des spans avec leur style : **gras**, _italique_, ~souligné~, `code`, [lien](url)
+func spansSummary(spans []TextSpan) string {
+	var out strings.Builder
+	for _, span := range spans {
+		text := span.Text
+		if style := span.Style; style != nil {
+			if style.Font == markdownCodeFont {
+				text = "`" + text + "`"
+			}
+			if style.Underline {
+				text = "~" + text + "~"
+			}
+			if style.Italic {
+				text = "_" + text + "_"
+			}
+			if style.Bold {
+				text = "**" + text + "**"
+			}
+		}
+		if span.Link != "" {
+			text = "[" + text + "](" + span.Link + ")"
+		}
+		out.WriteString(text)
+	}
+	return out.String()
+}
+
+// blockSummary décrit un bloc converti : paragraphe, titre (taille), citation, élément de liste ou ligne
+func blockSummary(element Element) string {
+	switch element.Type {
+	case "line":
+		return "hr"
+	case "list":
+		item := element.Items[0].([]Element)[0]
+		return fmt.Sprintf("li %s niveau %g : %s", element.Bullet, element.Style.Margin[3]/listIndent, spansSummary(item.Spans))
+	case "text":
+		style := element.Style
+		switch {
+		case style.KeepWithNext:
+			return fmt.Sprintf("titre %gpt : %s", style.Size, spansSummary(element.Spans))
+		case style.Italic && style.Margin[3] > 0:
+			return "citation : " + spansSummary(element.Spans)
+		}
+		return "p : " + spansSummary(element.Spans)
+	}
+	return element.Type
+}
+
+func TestHTMLElements(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"paragraphe", "<p>Un <b>gras</b> et <i>italique</i></p>", []string{"p : Un **gras** et _italique_"}},
+		{"styles en ligne", "<strong>a</strong><em>b</em><u>c</u><code>d</code>", []string{"p : **a**_b_~c~`d`"}},
+		{"styles imbriqués", "<i>a <b>b</b></i>", []string{"p : _a _**_b_**"}},
+		{"lien", `Voir <a href="https://example.com">le site</a>`, []string{"p : Voir [le site](https://example.com)"}},
+		{"saut de ligne", "un<br>deux<br/>trois", []string{"p : un\ndeux\ntrois"}},
+		{"paragraphes et div", "<p>a</p><div>b</div>c", []string{"p : a", "p : b", "p : c"}},
+		{"titres", "<h1>Titre</h1><p>texte</p><h3>Section</h3><h6>Note</h6>", []string{"titre 18pt : Titre", "p : texte", "titre 12.5pt : Section", "titre 10pt : Note"}},
+		{"citation", "<blockquote>Cité</blockquote>", []string{"citation : Cité"}},
+		{"liste à puces", "<ul><li>un</li><li>deux</li></ul>", []string{"li • niveau 0 : un", "li • niveau 0 : deux"}},
+		{"liste numérotée imbriquée", "<ul><li>un<ol><li>a</li><li>b</li></ol></li></ul>", []string{"li • niveau 0 : un", "li 1. niveau 1 : a", "li 2. niveau 1 : b"}},
+		{"élément de liste sans liste", "<li>seul</li>", []string{"li • niveau 0 : seul"}},
+		{"ligne horizontale", "<p>a</p><hr><p>b</p>", []string{"p : a", "hr", "p : b"}},
+		{"script et style supprimés", "<script>alert(1)</script><style>p {}</style>texte", []string{"p : texte"}},
+		{"balise inconnue", "<span>texte</span>", []string{"p : texte"}},
+		{"entités et blancs", "a&amp;b   \n  c", []string{"p : a&b c"}},
+		{"blocs vides abandonnés", "<p> </p><div></div>", nil},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, element := range htmlElements(Element{Type: "html", Content: test.content}) {
+			got = append(got, blockSummary(element))
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s : %q, attendu %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package template
+
+import (
+	"embed"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// --- Césure (algorithme de Liang, motifs TeX) ---
+//
+// Les motifs de césure TeX (hyph-utf8) sont embarqués pour le français, l'anglais et
+// l'allemand. Un motif associe des lettres à des valeurs placées entre elles : pour
+// chaque position d'un mot, la plus grande valeur des motifs qui la couvrent est
+// retenue et une valeur impaire autorise la coupure. La césure est utilisée par
+// wrapRuns lorsque style.hyphenate est activé et que le document déclare sa langue.
+
+//go:embed hyphen/*.txt
+var hyphenFiles embed.FS
+
+// hyphenator coupe les mots d'une langue
+type hyphenator struct {
+	patterns   map[string][]uint8 // lettres du motif -> valeurs (une de plus que de lettres)
+	exceptions map[string][]int   // mot -> positions de coupure (en lettres)
+	maxLen     int                // longueur du plus long motif, en lettres
+	leftMin    int                // lettres minimales avant une coupure
+	rightMin   int                // lettres minimales après une coupure
+}
+
+// hyphenMins donne les longueurs minimales (avant, après la coupure) de chaque langue
+var hyphenMins = map[string][2]int{
+	"fr": {2, 2},
+	"en": {2, 3},
+	"de": {2, 2},
+}
+
+var (
+	hyphenatorsMu sync.Mutex
+	hyphenators   = map[string]*hyphenator{}
+)
+
+// hyphenatorFor retourne le découpeur d'une langue ("fr", "en-US", "de_DE"...), chargé à la
+// première utilisation, ou nil si la langue n'a pas de motifs
+func hyphenatorFor(language string) *hyphenator {
+	lang := strings.ToLower(language)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	mins, ok := hyphenMins[lang]
+	if !ok {
+		return nil
+	}
+
+	hyphenatorsMu.Lock()
+	defer hyphenatorsMu.Unlock()
+	if h, ok := hyphenators[lang]; ok {
+		return h
+	}
+
+	data, err := hyphenFiles.ReadFile("hyphen/" + lang + ".txt")
+	if err != nil {
+		return nil
+	}
+	h := parseHyphenPatterns(string(data))
+	h.leftMin, h.rightMin = mins[0], mins[1]
+	hyphenators[lang] = h
+	return h
+}
+
+// parseHyphenPatterns lit un fichier de motifs : un motif ("hy3ph", ".ach4") ou une exception
+// ("ta-ble") par ligne, les lignes commençant par % sont des commentaires
+func parseHyphenPatterns(data string) *hyphenator {
+	h := &hyphenator{patterns: map[string][]uint8{}, exceptions: map[string][]int{}}
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%") {
+			continue
+		}
+
+		if strings.Contains(line, "-") {
+			var positions []int
+			letters := 0
+			for _, r := range line {
+				if r == '-' {
+					positions = append(positions, letters)
+				} else {
+					letters++
+				}
+			}
+			h.exceptions[strings.ReplaceAll(line, "-", "")] = positions
+			continue
+		}
+
+		var letters []rune
+		values := []uint8{0}
+		for _, r := range line {
+			if r >= '0' && r <= '9' {
+				values[len(values)-1] = uint8(r - '0')
+				continue
+			}
+			letters = append(letters, r)
+			values = append(values, 0)
+		}
+		h.patterns[string(letters)] = values
+		if len(letters) > h.maxLen {
+			h.maxLen = len(letters)
+		}
+	}
+	return h
+}
+
+// breaks retourne les positions de coupure d'un mot composé uniquement de lettres,
+// en nombre de lettres depuis le début du mot
+func (h *hyphenator) breaks(word []rune) []int {
+	n := len(word)
+	if n < h.leftMin+h.rightMin {
+		return nil
+	}
+
+	lower := strings.ToLower(string(word))
+	if positions, ok := h.exceptions[lower]; ok {
+		return positions
+	}
+
+	// Mot entouré de points (bornes) ; values[i] est la valeur avant la lettre i de dotted
+	dotted := []rune("." + lower + ".")
+	values := make([]uint8, len(dotted)+1)
+	for i := range dotted {
+		for j := i + 1; j <= len(dotted) && j-i <= h.maxLen; j++ {
+			pattern, ok := h.patterns[string(dotted[i:j])]
+			if !ok {
+				continue
+			}
+			for k, v := range pattern {
+				if v > values[i+k] {
+					values[i+k] = v
+				}
+			}
+		}
+	}
+
+	var positions []int
+	for p := h.leftMin; p <= n-h.rightMin; p++ {
+		if values[p+1]%2 == 1 {
+			positions = append(positions, p)
+		}
+	}
+	return positions
+}
+
+// hyphenPoints retourne les coupures possibles d'un mot (positions en octets) : la ponctuation
+// qui l'entoure est ignorée, un mot composé peut aussi être coupé après ses traits d'union.
+// explicit indique que la coupure suit un trait d'union existant (pas de tiret ajouté).
+func (h *hyphenator) hyphenPoints(word string) (points []int, explicit []bool) {
+	start := 0
+	for start < len(word) {
+		// Partie de mot : lettres et apostrophes
+		r, size := utf8.DecodeRuneInString(word[start:])
+		if !isHyphenLetter(r) {
+			if r == '-' && start > 0 && start+size < len(word) {
+				points = append(points, start+size)
+				explicit = append(explicit, true)
+			}
+			start += size
+			continue
+		}
+
+		end := start
+		var letters []rune
+		var offsets []int
+		for end < len(word) {
+			r, size := utf8.DecodeRuneInString(word[end:])
+			if !isHyphenLetter(r) {
+				break
+			}
+			letters = append(letters, normalizeApostrophe(r))
+			offsets = append(offsets, end)
+			end += size
+		}
+
+		for _, p := range h.breaks(letters) {
+			points = append(points, offsets[p])
+			explicit = append(explicit, false)
+		}
+		start = end
+	}
+	return points, explicit
+}
+
+func isHyphenLetter(r rune) bool {
+	return unicode.IsLetter(r) || r == '\'' || r == '’'
+}
+
+func normalizeApostrophe(r rune) rune {
+	if r == '’' {
+		return '\''
+	}
+	return r
+}
+
+// hyphenate cherche la dernière coupure d'un mot dont la première partie, tiret compris,
+// tient dans width selon measure (texte encodé par encode pour la police) ; head inclut le tiret ajouté
+func (h *hyphenator) hyphenate(word string, width float64, encode func(string) string, measure func(string) float64) (head, rest string, ok bool) {
+	points, explicit := h.hyphenPoints(word)
+	for i := len(points) - 1; i >= 0; i-- {
+		head = word[:points[i]]
+		if !explicit[i] {
+			head += "-"
+		}
+		if measure(encode(head)) <= width {
+			return head, word[points[i]:], true
+		}
+	}
+	return "", word, false
+}
+
+// runHyphenator retourne le découpeur à utiliser pour un run, ou nil si la césure est désactivée
+func (b *PDFBuilder) runHyphenator(style *Style) *hyphenator {
+	if style == nil || !style.Hyphenate || b.config.Language == "" {
+		return nil
+	}
+	return hyphenatorFor(b.config.Language)
+}
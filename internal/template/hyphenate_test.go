@@ -0,0 +1,131 @@
+package template
+
+import (
+	"slices"
+	"testing"
+	"unicode/utf8"
+)
+
+// marked écrit un mot avec ses coupures : « - » pour une coupure des motifs, « | » après un trait d'union
+func marked(h *hyphenator, word string) string {
+	points, explicit := h.hyphenPoints(word)
+	out, last := "", 0
+	for i, p := range points {
+		out += word[last:p]
+		if explicit[i] {
+			out += "|"
+		} else {
+			out += "-"
+		}
+		last = p
+	}
+	return out + word[last:]
+}
+
+func TestParseHyphenPatterns(t *testing.T) {
+	h := parseHyphenPatterns("% commentaire\n.ach4\n\nhy3ph\n  ta-ble  \n")
+	tests := []struct {
+		pattern string
+		want    []uint8
+	}{
+		{".ach", []uint8{0, 0, 0, 0, 4}},
+		{"hyph", []uint8{0, 0, 3, 0, 0}},
+	}
+	for _, test := range tests {
+		if got := h.patterns[test.pattern]; !slices.Equal(got, test.want) {
+			t.Errorf("motif %q : %v, attendu %v", test.pattern, got, test.want)
+		}
+	}
+	if len(h.patterns) != 2 || h.maxLen != 4 {
+		t.Errorf("%d motif(s), longueur maximale %d, attendu 2 et 4", len(h.patterns), h.maxLen)
+	}
+	if got := h.exceptions["table"]; !slices.Equal(got, []int{2}) {
+		t.Errorf("exception table : %v, attendu [2]", got)
+	}
+}
+
+func TestHyphenBreaks(t *testing.T) {
+	// Motifs de Liang : la plus grande valeur l'emporte, une valeur impaire permet la coupure
+	h := parseHyphenPatterns("1ba\nbab1")
+	h.leftMin, h.rightMin = 1, 1
+	inhibited := parseHyphenPatterns("1ba\n.a2b")
+	inhibited.leftMin, inhibited.rightMin = 1, 1
+
+	tests := []struct {
+		name string
+		h    *hyphenator
+		word string
+		want []int
+	}{
+		{"motifs", h, "ababa", []int{1, 3, 4}},
+		{"valeur paire plus forte", inhibited, "ababa", []int{3}},
+		{"mot trop court", h, "a", nil},
+		{"majuscules", h, "ABABA", []int{1, 3, 4}},
+		{"longueurs minimales", &hyphenator{patterns: h.patterns, maxLen: h.maxLen, leftMin: 2, rightMin: 2}, "ababa", []int{3}},
+	}
+	for _, test := range tests {
+		if got := test.h.breaks([]rune(test.word)); !slices.Equal(got, test.want) {
+			t.Errorf("%s : coupures de %q %v, attendu %v", test.name, test.word, got, test.want)
+		}
+	}
+}
+
+func TestHyphenLanguages(t *testing.T) {
+	tests := []struct {
+		language, word, want string
+	}{
+		{"fr", "typographie", "ty-po-gra-phie"},
+		{"fr-FR", "ordinateur", "or-di-na-teur"},
+		{"fr", "aujourd’hui", "au-jour-d’hui"},
+		{"fr", "porte-monnaie", "porte-|mon-naie"},
+		{"fr", "«typographie»", "«ty-po-gra-phie»"},
+		{"en", "project", "pro-ject"},
+		{"en-US", "computer", "com-puter"}, // trois lettres au moins après la coupure
+		{"en_US", "table", "ta-ble"},       // exception
+		{"de", "Silbentrennung", "Sil-ben-tren-nung"},
+		{"DE", "Donaudampfschifffahrt", "Do-nau-dampf-schiff-fahrt"},
+	}
+	for _, test := range tests {
+		h := hyphenatorFor(test.language)
+		if h == nil {
+			t.Fatalf("pas de motifs pour %q", test.language)
+		}
+		if got := marked(h, test.word); got != test.want {
+			t.Errorf("%s : %q, attendu %q", test.language, got, test.want)
+		}
+	}
+
+	if hyphenatorFor("fr") != hyphenatorFor("fr-CA") {
+		t.Error("les motifs d'une langue sont chargés plusieurs fois")
+	}
+	if h := hyphenatorFor("es"); h != nil {
+		t.Error("motifs trouvés pour une langue non embarquée")
+	}
+}
+
+func TestHyphenate(t *testing.T) {
+	h := hyphenatorFor("fr")
+	identity := func(s string) string { return s }
+	runes := func(s string) float64 { return float64(utf8.RuneCountInString(s)) }
+
+	tests := []struct {
+		word       string
+		width      float64
+		head, rest string
+		ok         bool
+	}{
+		{"typographie", 10, "typogra-", "phie", true},
+		{"typographie", 6, "typo-", "graphie", true},
+		{"typographie", 3, "ty-", "pographie", true},
+		{"typographie", 2, "", "typographie", false},
+		{"porte-monnaie", 12, "porte-mon-", "naie", true},
+		{"porte-monnaie", 9, "porte-", "monnaie", true}, // après le trait d'union : pas de tiret ajouté
+	}
+	for _, test := range tests {
+		head, rest, ok := h.hyphenate(test.word, test.width, identity, runes)
+		if head != test.head || rest != test.rest || ok != test.ok {
+			t.Errorf("%q sur %v : (%q, %q, %v), attendu (%q, %q, %v)",
+				test.word, test.width, head, rest, ok, test.head, test.rest, test.ok)
+		}
+	}
+}
@@ -0,0 +1,577 @@
+package template
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// --- Moteur de mise en page en deux passes ---
+//
+// La phase de mise en page (Layout) transforme les éléments du template en boîtes
+// positionnées sur des pages, à partir des mesures sans rendu. La phase de dessin
+// (paint) parcourt ensuite ces boîtes page par page. Les sauts de page sont donc
+// décidés avant tout dessin, ce qui permet de connaître le nombre total de pages.
+
+// Box est un élément (ou un fragment d'élément) positionné par la mise en page.
+// Les coordonnées sont en mm depuis le coin supérieur gauche de la page.
+type Box struct {
+	Element Element
+	Page    int // numéro de page, à partir de 1
+	X, Y    float64
+	Width   float64
+	Height  float64
+
+	// Fragment d'un élément découpé entre plusieurs pages : lignes de texte,
+	// lignes de tableau ou lignes de grille [From, To). To == 0 : élément entier.
+	// Pour un cadre (box), From est le numéro du fragment et To le nombre de fragments.
+	From, To int
+
+	// Enfants positionnés (cellules d'une ligne de grille)
+	Children []Box
+
+	rows []TableRow // lignes de tableau déjà résolues
+	cell bool       // texte d'une cellule de grille, dessiné avec les métriques de renderTextInWidth
+}
+
+// pageTokenRe reconnaît les numéros de page résolus au dessin : {{pageNumber}} et {{totalPages}}
+var pageTokenRe = regexp.MustCompile(`\{\{\s*(pageNumber|totalPages)\s*\}\}`)
+
+// layoutState est le curseur de la mise en page
+type layoutState struct {
+	page   int
+	y      float64
+	top    float64 // haut de la zone de contenu
+	bottom float64 // limite de saut de page
+	boxes  []Box
+}
+
+func (s *layoutState) newPage() {
+	s.page++
+	s.y = s.top
+}
+
+func (s *layoutState) atPageTop() bool {
+	return s.y <= s.top
+}
+
+func (s *layoutState) fits(height float64) bool {
+	return s.y+height <= s.bottom
+}
+
+// Layout calcule la position de tous les éléments du template, page par page, sans rien dessiner
+func (b *PDFBuilder) Layout() []Box {
+	b.setupFonts()
+
+	_, pageHeight := b.pdf.GetPageSize()
+	state := &layoutState{
+		page:   1,
+		y:      b.margins.top,
+		top:    b.margins.top,
+		bottom: pageHeight - b.margins.bottom,
+	}
+
+	b.layoutElements(state, b.config.Elements)
+
+	b.totalPages = 1
+	for _, box := range state.boxes {
+		if box.Page > b.totalPages {
+			b.totalPages = box.Page
+		}
+	}
+
+	return state.boxes
+}
+
+// layoutElements place une suite d'éléments en honorant les sauts de page explicites
+// (pageBreak, breakBefore) et les contraintes keepTogether / keepWithNext
+func (b *PDFBuilder) layoutElements(s *layoutState, elements []Element) {
+	pageContentHeight := s.bottom - s.top
+
+	for i, element := range elements {
+		if element.Position != nil {
+			b.layoutPositioned(s, element)
+			continue
+		}
+		if element.Type == "pageBreak" {
+			if !s.atPageTop() {
+				s.newPage()
+			}
+			continue
+		}
+
+		style := element.Style
+		if style != nil && style.BreakBefore {
+			if !s.atPageTop() {
+				s.newPage()
+			}
+		} else if style != nil && (style.KeepWithNext || style.KeepTogether) {
+			needed := 0.0
+			if style.KeepWithNext {
+				needed = b.keepWithNextHeight(elements[i:], b.area.width)
+			} else {
+				needed = b.measureElement(element, b.area.width)
+			}
+
+			// Passer à la page suivante seulement si l'ensemble y tiendrait ; sinon l'élément
+			// est découpé normalement
+			if !s.fits(needed) && !s.atPageTop() && needed <= pageContentHeight {
+				s.newPage()
+			}
+		}
+
+		b.layoutElement(s, element)
+	}
+}
+
+// keepWithNextHeight mesure une chaîne d'éléments keepWithNext suivie du début de l'élément suivant
+func (b *PDFBuilder) keepWithNextHeight(elements []Element, width float64) float64 {
+	height := 0.0
+	for _, element := range elements {
+		if element.Type == "pageBreak" {
+			break
+		}
+		if element.Position != nil {
+			continue
+		}
+		if element.Style == nil || !element.Style.KeepWithNext {
+			// Élément suivant : entier s'il est keepTogether, sinon sa marge haute et son premier morceau insécable
+			if element.Style != nil && element.Style.KeepTogether {
+				return height + b.measureElement(element, width)
+			}
+			if element.Style != nil && len(element.Style.Margin) > 0 {
+				height += parseSpacing(element.Style.Margin).Top
+			}
+			return height + b.measureLeadingContent(element, width)
+		}
+		height += b.measureElement(element, width)
+	}
+	return height
+}
+
+// measureLeadingContent mesure la première partie insécable d'un élément : en-têtes et première
+// ligne d'un tableau, première ligne d'une grille, première ligne d'un texte multiligne
+func (b *PDFBuilder) measureLeadingContent(element Element, width float64) float64 {
+	switch element.Type {
+	case "table":
+		if len(element.Columns) == 0 || len(b.tableRows(element)) == 0 {
+			return 0
+		}
+		return 2 * tableRowHeight
+	case "grid":
+		layout, ok := newGridLayout(element, width)
+		if !ok {
+			return 0
+		}
+		_, rowHeight := b.measureGridRow(layout, layout.rows[0])
+		return rowHeight
+	case "box":
+		if height := fixedHeight(element.Style); height > 0 {
+			return height
+		}
+		frame := newBoxFrame(element.Style, width)
+		_, innerWidth := frame.inner()
+		return frame.padding.Top + b.keepWithNextHeight(element.Children, innerWidth)
+	case "list":
+		if len(element.Items) == 0 {
+			return 0
+		}
+		contentWidth, _ := b.getContentArea(width, element.Style)
+		return b.keepWithNextHeight(listItemElements(element, element.Items[0]), contentWidth-b.listIndentOf(element))
+	case "markdown", "html":
+		contentWidth, _ := b.getContentArea(width, element.Style)
+		return b.keepWithNextHeight(blockElements(element, contentWidth), contentWidth)
+	case "text":
+		if isMultiline(element) {
+			contentWidth, _ := b.getContentArea(width, element.Style)
+			return textLineHeight(b.fitText(element, contentWidth).Style, 8)
+		}
+	}
+	return b.measureContent(element, width)
+}
+
+func (b *PDFBuilder) layoutElement(s *layoutState, element Element) {
+	var margin Spacing
+	if element.Style != nil && len(element.Style.Margin) > 0 {
+		margin = parseSpacing(element.Style.Margin)
+	}
+
+	// Marge haute (abandonnée si l'élément passe en haut de la page suivante)
+	if margin.Top > 0 {
+		s.y += margin.Top
+	}
+
+	x, width := b.area.x, b.area.width
+
+	switch element.Type {
+	case "table":
+		b.layoutTable(s, element, x, width)
+	case "grid":
+		b.layoutGrid(s, element, x, width)
+	case "box":
+		b.layoutBox(s, element, x, width)
+	case "list":
+		b.layoutList(s, element, x, width)
+	case "markdown", "html":
+		b.layoutBlocks(s, element, x, width)
+	case "text":
+		if isMultiline(element) {
+			b.layoutText(s, element, x, width)
+		} else {
+			b.placeBox(s, element, x, width, b.measureContent(element, width))
+		}
+	case "space":
+		// Un espace ne se reporte pas en haut de la page suivante
+		height := b.measureSpace(element)
+		if s.fits(height) {
+			s.y += height
+		} else {
+			s.newPage()
+		}
+	default:
+		b.placeBox(s, element, x, width, b.measureContent(element, width))
+	}
+
+	if margin.Bottom > 0 {
+		s.y += margin.Bottom
+	}
+}
+
+// placeBox place un élément insécable, sur la page suivante s'il ne tient pas
+func (b *PDFBuilder) placeBox(s *layoutState, element Element, x, width, height float64) {
+	if !s.fits(height) && !s.atPageTop() {
+		s.newPage()
+	}
+	s.boxes = append(s.boxes, Box{Element: element, Page: s.page, X: x, Y: s.y, Width: width, Height: height})
+	s.y += height
+}
+
+// isMultiline indique si un texte peut occuper plusieurs lignes (et donc être découpé entre pages)
+func isMultiline(element Element) bool {
+	return len(element.Spans) > 0 || !noWrap(element.Style)
+}
+
+// layoutText découpe un texte multiligne en fragments de lignes entières
+func (b *PDFBuilder) layoutText(s *layoutState, element Element, x, width float64) {
+	contentWidth, _ := b.getContentArea(width, element.Style)
+	element = b.fitText(element, contentWidth)
+
+	lines := 0
+	if b.richLayout(element) {
+		lines = len(b.richLines(element, contentWidth))
+	} else {
+		lines = b.countLines(element.Style, textContent(element), contentWidth)
+	}
+	b.splitTextLines(s, Box{Element: element, X: x, Width: width}, lines, textLineHeight(element.Style, 8))
+}
+
+// layoutCellText place le texte d'une cellule de grille découpée entre pages avec les métriques
+// de measureTextInWidth : en un bloc s'il tient, sinon en fragments de lignes entières
+func (b *PDFBuilder) layoutCellText(s *layoutState, element Element, x, width float64) {
+	element = b.fitText(element, width)
+	box := Box{Element: element, X: x, Width: width, cell: true}
+
+	height := b.measureTextInWidth(element, width)
+	lines := b.textLineCount(element, width)
+	if s.fits(height) || lines <= 1 {
+		if !s.fits(height) && !s.atPageTop() {
+			s.newPage()
+		}
+		box.Page, box.Y, box.Height = s.page, s.y, height
+		s.boxes = append(s.boxes, box)
+		s.y += height
+		return
+	}
+	b.splitTextLines(s, box, lines, textLineHeight(element.Style, 5))
+}
+
+// splitTextLines place les lignes d'un texte page par page : chaque fragment reprend box
+// avec sa page, sa position et ses lignes [From, To)
+func (b *PDFBuilder) splitTextLines(s *layoutState, box Box, lines int, lineHeight float64) {
+	for from := 0; from < lines; {
+		available := int(math.Floor((s.bottom-s.y)/lineHeight + 1e-9))
+		if available <= 0 {
+			if !s.atPageTop() {
+				s.newPage()
+				continue
+			}
+			available = 1
+		}
+
+		to := from + available
+		if to > lines {
+			to = lines
+		}
+
+		fragment := box
+		fragment.Page, fragment.Y, fragment.Height = s.page, s.y, float64(to-from)*lineHeight
+		fragment.From, fragment.To = from, to
+		s.boxes = append(s.boxes, fragment)
+		s.y += fragment.Height
+
+		from = to
+		if from < lines {
+			s.newPage()
+		}
+	}
+}
+
+// layoutTable découpe un tableau en fragments par page : les en-têtes sont répétés, un en-tête de
+// groupe reste avec sa première ligne et la place des lignes de report est réservée
+func (b *PDFBuilder) layoutTable(s *layoutState, element Element, x, width float64) {
+	rows := b.tableRows(element)
+	if len(element.Columns) == 0 || len(rows) == 0 {
+		return
+	}
+	// Colonnes réduites à la largeur disponible (cellule de grille, cadre...)
+	element.Columns = fitColumns(element.Columns, width)
+
+	carryColumn := -1
+	if element.CarryForward != nil {
+		carryColumn = carryForwardColumn(element.Columns, element.CarryForward.Field)
+	}
+	hasCarry := func(upTo int) bool {
+		if carryColumn < 0 {
+			return false
+		}
+		_, ok := runningTotal(rows[:upTo], carryColumn)
+		return ok
+	}
+
+	// Les en-têtes ne restent pas seuls en bas de page
+	if !s.fits(2*tableRowHeight) && !s.atPageTop() {
+		s.newPage()
+	}
+
+	from := 0
+	fragmentY := s.y
+	s.y += tableRowHeight // en-têtes
+
+	closeFragment := func(to int) {
+		s.boxes = append(s.boxes, Box{
+			Element: element, Page: s.page, X: x, Y: fragmentY, Width: width, Height: s.y - fragmentY,
+			From: from, To: to, rows: rows,
+		})
+	}
+
+	for i, row := range rows {
+		needed := tableRowHeight
+		if row.kind == rowGroupHeader && i+1 < len(rows) {
+			needed += tableRowHeight
+		}
+		if carryColumn >= 0 && i+1 < len(rows) {
+			needed += tableRowHeight
+		}
+
+		if !s.fits(needed) && i > from {
+			if hasCarry(i) {
+				s.y += tableRowHeight // « À reporter »
+			}
+			closeFragment(i)
+			s.newPage()
+
+			from = i
+			fragmentY = s.y
+			if isDataTable(element) {
+				s.y += tableRowHeight // en-têtes répétés
+			}
+			if hasCarry(i) {
+				s.y += tableRowHeight // « Report »
+			}
+		}
+
+		s.y += tableRowHeight
+	}
+
+	closeFragment(len(rows))
+}
+
+// layoutGrid place les lignes d'une grille ; une ligne qui ne tient pas passe à la page suivante,
+// une ligne plus haute qu'une page est découpée (voir splitGridRow)
+func (b *PDFBuilder) layoutGrid(s *layoutState, element Element, x, width float64) {
+	layout, ok := newGridLayout(element, width)
+	if !ok {
+		return
+	}
+	startPage, startY := s.page, s.y
+
+	defaultVAlign := ""
+	if element.Style != nil {
+		defaultVAlign = element.Style.VAlign
+	}
+
+	current := -1 // index du fragment en cours dans s.boxes
+	first := -1   // premier fragment, qui porte les enfants positionnés
+	for r, row := range layout.rows {
+		heights, rowHeight := b.measureGridRow(layout, row)
+
+		if !s.fits(rowHeight) && !s.atPageTop() && rowHeight <= s.bottom-s.top {
+			s.newPage()
+			current = -1
+		}
+		if !s.fits(rowHeight) {
+			b.splitGridRow(s, layout, row, x)
+			s.y += layout.rowGap
+			current = -1
+			continue
+		}
+		if current < 0 {
+			s.boxes = append(s.boxes, Box{Element: element, Page: s.page, X: x, Y: s.y, Width: width, From: r})
+			current = len(s.boxes) - 1
+			if first < 0 {
+				first = current
+			}
+		}
+
+		for i, cell := range row {
+			offset, cellWidth := layout.cellBox(cell)
+
+			valign := defaultVAlign
+			if cell.element.Style != nil && cell.element.Style.VAlign != "" {
+				valign = cell.element.Style.VAlign
+			}
+
+			y := s.y
+			switch valign {
+			case "middle":
+				y += (rowHeight - heights[i]) / 2
+			case "bottom":
+				y += rowHeight - heights[i]
+			}
+
+			fragment := &s.boxes[current]
+			fragment.Children = append(fragment.Children, Box{
+				Element: cell.element, Page: s.page, X: x + offset, Y: y, Width: cellWidth, Height: heights[i],
+			})
+		}
+
+		s.y += rowHeight + layout.rowGap
+
+		fragment := &s.boxes[current]
+		fragment.To = r + 1
+		fragment.Height = s.y - fragment.Y
+	}
+
+	for _, child := range element.Children {
+		if child.Position == nil {
+			continue
+		}
+		if first < 0 {
+			// Toutes les lignes ont été découpées : fragment vide pour porter les enfants positionnés
+			s.boxes = append(s.boxes, Box{Element: element, Page: startPage, X: x, Y: startY, Width: width})
+			first = len(s.boxes) - 1
+		}
+		fragment := &s.boxes[first]
+		fragment.Children = append(fragment.Children, Box{Element: child, Page: fragment.Page})
+	}
+}
+
+// splitGridRow place une ligne de grille plus haute qu'une page : chaque enfant est mis en page
+// dans sa colonne comme dans le flux, à partir du haut de la ligne, et découpé entre les pages
+// (tableaux, textes, cadres...). L'alignement vertical est ignoré ; la ligne se termine sous
+// l'enfant qui finit le plus bas.
+func (b *PDFBuilder) splitGridRow(s *layoutState, layout gridLayout, row []gridCell, x float64) {
+	start := len(s.boxes)
+	startPage, startY := s.page, s.y
+	endPage, endY := s.page, s.y
+
+	for _, cell := range row {
+		offset, cellWidth := layout.cellBox(cell)
+		s.page, s.y = startPage, startY
+		b.withArea(x+offset, cellWidth, func() {
+			if cell.element.Type == "text" && cell.element.Position == nil {
+				b.layoutCellText(s, cell.element, x+offset, cellWidth)
+			} else {
+				b.layoutElement(s, cell.element)
+			}
+		})
+		if s.page > endPage || (s.page == endPage && s.y > endY) {
+			endPage, endY = s.page, s.y
+		}
+	}
+	s.page, s.y = endPage, endY
+
+	// paint dessine les boîtes dans l'ordre des pages
+	cells := s.boxes[start:]
+	sort.SliceStable(cells, func(i, j int) bool { return cells[i].Page < cells[j].Page })
+}
+
+// paint dessine les boîtes page par page ; les sauts de page ont été décidés par Layout
+func (b *PDFBuilder) paint(boxes []Box) {
+	b.pdf.SetAutoPageBreak(false, b.margins.bottom)
+
+	page := 1
+	b.pdf.AddPage()
+	for _, box := range boxes {
+		for page < box.Page {
+			b.pdf.AddPage()
+			page++
+		}
+		b.paintBox(box)
+	}
+}
+
+func (b *PDFBuilder) paintBox(box Box) {
+	element := b.resolvePageTokens(box.Element, box.Page)
+
+	if element.Position != nil {
+		b.renderPositioned(element)
+		return
+	}
+
+	b.pdf.SetXY(box.X, box.Y)
+	b.withArea(box.X, box.Width, func() {
+		switch element.Type {
+		case "text":
+			if box.cell {
+				b.renderTextInWidthLines(element, box.From, box.To)
+			} else {
+				b.renderTextLines(element, box.From, box.To)
+			}
+		case "table":
+			b.renderTableRows(element, box.rows, box.From, box.To)
+		case "grid":
+			for _, child := range box.Children {
+				cell := b.resolvePageTokens(child.Element, box.Page)
+				b.pdf.SetXY(child.X, child.Y)
+				b.renderElementInWidth(cell, child.X, child.Width)
+			}
+		case "line":
+			b.renderLine(element)
+		case "image":
+			b.renderImage(element)
+		case "box":
+			b.paintBoxFrame(element, box)
+		}
+	})
+}
+
+// resolvePageTokens remplace {{pageNumber}} et {{totalPages}} dans le contenu texte et les spans d'un élément
+func (b *PDFBuilder) resolvePageTokens(element Element, page int) Element {
+	replace := func(text string) string {
+		if !strings.Contains(text, "{{") {
+			return text
+		}
+		return pageTokenRe.ReplaceAllStringFunc(text, func(token string) string {
+			if strings.Contains(token, "totalPages") {
+				return strconv.Itoa(b.totalPages)
+			}
+			return strconv.Itoa(page)
+		})
+	}
+
+	if content, ok := element.Content.(string); ok {
+		element.Content = replace(content)
+	}
+	if len(element.Spans) > 0 {
+		spans := make([]TextSpan, len(element.Spans))
+		for i, span := range element.Spans {
+			span.Text = replace(span.Text)
+			spans[i] = span
+		}
+		element.Spans = spans
+	}
+	return element
+}
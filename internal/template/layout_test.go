@@ -0,0 +1,179 @@
+package template
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// checkBoxesInPage vérifie que chaque boîte (et ses enfants) tient entre les marges haute et basse
+func checkBoxesInPage(t *testing.T, builder *PDFBuilder, boxes []Box) {
+	t.Helper()
+	_, pageHeight := builder.pdf.GetPageSize()
+	bottom := pageHeight - builder.margins.bottom
+	for _, box := range boxes {
+		if box.Element.Position != nil {
+			continue
+		}
+		if box.Y < builder.margins.top-1e-9 || box.Y+box.Height > bottom+1e-9 {
+			t.Errorf("%s page %d : de %.1f à %.1f mm, hors de la zone [%.1f, %.1f]",
+				box.Element.Type, box.Page, box.Y, box.Y+box.Height, builder.margins.top, bottom)
+		}
+		checkBoxesInPage(t, builder, box.Children)
+	}
+}
+
+func TestGridRowSplit(t *testing.T) {
+	table, _ := tableTemplate(60, false)
+	template := Template{Elements: []Element{
+		{Type: "text", Content: "Avant la grille"},
+		{Type: "grid", GridColumns: 2, Children: []Element{
+			{Type: "text", Content: "Colonne de gauche"},
+			table.Elements[0],
+			{Type: "text", Content: "Ligne suivante"},
+		}},
+		{Type: "text", Content: "Après la grille"},
+	}}
+
+	builder := NewPDFBuilder(template)
+	boxes := builder.Layout()
+	checkBoxesInPage(t, builder, boxes)
+
+	// Les boîtes sont dans l'ordre des pages et le tableau est découpé en plusieurs fragments
+	fragments, page := 0, 0
+	for _, box := range boxes {
+		if box.Page < page {
+			t.Errorf("boîte %s de la page %d après la page %d", box.Element.Type, box.Page, page)
+		}
+		page = box.Page
+		if box.Element.Type == "table" {
+			fragments++
+		}
+	}
+	if fragments < 2 {
+		t.Errorf("tableau en %d fragment(s), attendu au moins 2", fragments)
+	}
+
+	last := boxes[len(boxes)-1]
+	if last.Element.Content != "Après la grille" || last.Page < 2 {
+		t.Errorf("dernier élément %q page %d, attendu « Après la grille » après la page 1", last.Element.Content, last.Page)
+	}
+
+	if _, err := builder.Build(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGridRowSplitCellMetrics(t *testing.T) {
+	// Les textes d'une ligne découpée sont mesurés comme ceux d'une ligne non découpée :
+	// lignes de 5 mm, une ligne seule occupe 7,5 mm
+	table, _ := tableTemplate(60, false)
+	short := Element{Type: "text", Content: "Colonne de gauche"}
+	long := Element{Type: "text", Content: strings.Repeat("texte de cellule ", 400)}
+	template := Template{Elements: []Element{
+		{Type: "grid", GridColumns: 3, Children: []Element{short, table.Elements[0], long}},
+		{Type: "grid", GridColumns: 3, Children: []Element{short}},
+	}}
+
+	builder := NewPDFBuilder(template)
+	boxes := builder.Layout()
+	checkBoxesInPage(t, builder, boxes)
+
+	layout, _ := newGridLayout(template.Elements[0], 180)
+	_, cellWidth := layout.cellBox(layout.rows[0][0])
+	shortHeight := builder.measureElementInWidth(short, cellWidth)
+	longHeight := builder.measureElementInWidth(long, cellWidth)
+
+	var shortBoxes []float64
+	longFragments, longTotal := 0, 0.0
+	for _, box := range boxes {
+		switch {
+		case box.Element.Type == "grid":
+			for _, child := range box.Children {
+				shortBoxes = append(shortBoxes, child.Height)
+			}
+		case box.Element.Content == short.Content:
+			shortBoxes = append(shortBoxes, box.Height)
+			if !box.cell {
+				t.Error("texte court d'une ligne découpée non marqué comme cellule")
+			}
+		case box.Element.Content == long.Content:
+			longFragments++
+			longTotal += box.Height
+			if !box.cell || math.Mod(box.Height, 5) > 1e-9 {
+				t.Errorf("fragment de %v mm (cellule %v), attendu un multiple de 5 mm", box.Height, box.cell)
+			}
+		}
+	}
+	if len(shortBoxes) != 2 || shortBoxes[0] != shortHeight || shortBoxes[1] != shortHeight {
+		t.Errorf("texte court : hauteurs %v, attendu %v dans les deux grilles", shortBoxes, shortHeight)
+	}
+	if longFragments < 2 || math.Abs(longTotal-longHeight) > 1e-9 {
+		t.Errorf("texte long : %d fragment(s), %v mm au total, attendu au moins 2 et %v mm", longFragments, longTotal, longHeight)
+	}
+
+	if _, err := builder.Build(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGridRowMovesToNextPage(t *testing.T) {
+	// Une ligne qui tient sur une page entière passe à la page suivante sans être découpée
+	table, _ := tableTemplate(20, false)
+	template := Template{Elements: []Element{
+		{Type: "space", Style: &Style{Height: 200}},
+		{Type: "grid", GridColumns: 2, Children: []Element{table.Elements[0], {Type: "text", Content: "À côté"}}},
+	}}
+
+	builder := NewPDFBuilder(template)
+	boxes := builder.Layout()
+	checkBoxesInPage(t, builder, boxes)
+	if len(boxes) != 1 || boxes[0].Element.Type != "grid" || boxes[0].Page != 2 {
+		t.Fatalf("attendu une seule boîte de grille en page 2, obtenu %d boîte(s)", len(boxes))
+	}
+}
+
+func TestLayoutPagination(t *testing.T) {
+	// Page A4 par défaut : 265 mm de contenu entre les marges, lignes de texte de 8 mm
+	const content = 297 - 12 - 20
+	text := func(s string, style *Style) Element { return Element{Type: "text", Content: s, Style: style} }
+	space := func(height float64) Element { return Element{Type: "space", Style: &Style{Height: height}} }
+	pageBreak := Element{Type: "pageBreak"}
+	lines := "un\ndeux\ntrois\nquatre"
+
+	tests := []struct {
+		name     string
+		elements []Element
+		want     string // contenu (lignes [from, to) d'un fragment) : page
+	}{
+		{"saut de page", []Element{text("a", nil), pageBreak, text("b", nil)}, "a:1 b:2"},
+		{"saut de page en haut de page ignoré", []Element{pageBreak, text("a", nil)}, "a:1"},
+		{"breakBefore", []Element{text("a", nil), text("b", &Style{BreakBefore: true})}, "a:1 b:2"},
+		{"breakBefore en haut de page", []Element{text("a", &Style{BreakBefore: true})}, "a:1"},
+		{"ligne qui ne tient plus", []Element{space(content - 4), text("a", nil)}, "a:2"},
+		{"espace non reporté", []Element{space(content - 4), space(20), text("a", nil), text("b", nil)}, "a:2 b:2"},
+		{"titre seul en bas de page", []Element{space(content - 12), text("titre", nil), text("a", nil)}, "titre:1 a:2"},
+		{"keepWithNext", []Element{space(content - 12), text("titre", &Style{KeepWithNext: true}), text("a", nil)}, "titre:2 a:2"},
+		{"texte découpé", []Element{space(content - 20), text(lines, nil)}, "[0,2):1 [2,4):2"},
+		{"keepTogether", []Element{space(content - 20), text(lines, &Style{KeepTogether: true})}, "[0,4):2"},
+		{"keepTogether plus haut qu'une page", []Element{space(content - 20), text(strings.Repeat("ligne\n", 40), &Style{KeepTogether: true})}, "[0,2):1 [2,35):2 [35,40):3"},
+	}
+	for _, test := range tests {
+		builder := NewPDFBuilder(Template{Elements: test.elements})
+		boxes := builder.Layout()
+		checkBoxesInPage(t, builder, boxes)
+
+		var got []string
+		for _, box := range boxes {
+			label := textContent(box.Element)
+			if strings.Contains(label, "\n") {
+				label = fmt.Sprintf("[%d,%d)", box.From, box.To)
+			}
+			got = append(got, fmt.Sprintf("%s:%d", label, box.Page))
+		}
+		if strings.Join(got, " ") != test.want {
+			t.Errorf("%s : %s, attendu %s", test.name, strings.Join(got, " "), test.want)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package template
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// --- Listes à puces et numérotées ---
+//
+// Chaque élément de liste est composé d'un marqueur (puce ou numéro) et d'un contenu
+// mis en page dans une zone en retrait : les lignes suivantes d'un texte long
+// s'alignent ainsi sur la première (retrait suspendu). Un élément peut contenir
+// plusieurs éléments, dont des listes imbriquées.
+
+const (
+	listIndent    = 6.0 // retrait par défaut du contenu (mm)
+	listMarkerGap = 1.5 // espace entre le marqueur et le contenu (mm)
+)
+
+// listBullets sont les puces par défaut selon le niveau d'imbrication
+var listBullets = []string{"•", "–", "·"}
+
+// listIndentOf retourne le retrait du contenu des éléments d'une liste : indent s'il est
+// précisé, sinon le retrait par défaut élargi au marqueur le plus large
+func (b *PDFBuilder) listIndentOf(element Element) float64 {
+	if element.Indent > 0 {
+		return element.Indent
+	}
+
+	saved := b.font
+	defer func() {
+		if saved.family != "" {
+			b.setFont(saved.family, saved.style, saved.size)
+		}
+	}()
+
+	indent := listIndent
+	b.applyFont(markerElement(element, "").Style)
+	for n := range element.Items {
+		width := b.pdf.GetStringWidth(b.encode(listMarker(element, n))) + 2*b.pdf.GetCellMargin() + listMarkerGap
+		if width > indent {
+			indent = width
+		}
+	}
+	return indent
+}
+
+// listMarker retourne le marqueur du n-ième élément (à partir de 0)
+func listMarker(element Element, n int) string {
+	if !element.Ordered {
+		if element.Bullet != "" {
+			return element.Bullet
+		}
+		return listBullets[element.listLevel%len(listBullets)]
+	}
+
+	start := 1
+	if element.Start != 0 {
+		start = element.Start
+	}
+	format := element.Numbering
+	if format == "" {
+		format = "1."
+	}
+	return formatListNumber(format, start+n)
+}
+
+// formatListNumber remplace le premier compteur du format ("1", "a", "A", "i" ou "I")
+// par le numéro, le reste du format est conservé ("a)" -> "c)", "(i)" -> "(iii)")
+func formatListNumber(format string, number int) string {
+	i := strings.IndexAny(format, "1aAiI")
+	if i < 0 {
+		return format + strconv.Itoa(number)
+	}
+
+	var counter string
+	switch format[i] {
+	case 'a':
+		counter = alphaNumber(number)
+	case 'A':
+		counter = strings.ToUpper(alphaNumber(number))
+	case 'i':
+		counter = strings.ToLower(romanNumber(number))
+	case 'I':
+		counter = romanNumber(number)
+	default:
+		counter = strconv.Itoa(number)
+	}
+	return format[:i] + counter + format[i+1:]
+}
+
+// alphaNumber convertit un numéro en lettres : 1 -> a, 26 -> z, 27 -> aa
+func alphaNumber(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	var letters []byte
+	for n > 0 {
+		n--
+		letters = append([]byte{byte('a' + n%26)}, letters...)
+		n /= 26
+	}
+	return string(letters)
+}
+
+// romanNumber convertit un numéro en chiffres romains majuscules (1 à 3999)
+func romanNumber(n int) string {
+	if n <= 0 || n >= 4000 {
+		return strconv.Itoa(n)
+	}
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+
+	var sb strings.Builder
+	for i, value := range values {
+		for n >= value {
+			sb.WriteString(symbols[i])
+			n -= value
+		}
+	}
+	return sb.String()
+}
+
+// listItemElements convertit un élément de liste en éléments : une chaîne devient un texte
+// au style de la liste, un tableau d'éléments est repris tel quel (listes imbriquées comprises)
+func listItemElements(element Element, item interface{}) []Element {
+	var elements []Element
+
+	switch v := item.(type) {
+	case string:
+		elements = []Element{listText(element, v)}
+	case Element:
+		elements = []Element{v}
+	case []Element:
+		elements = append(elements, v...)
+	case []interface{}:
+		for _, child := range v {
+			if text, ok := child.(string); ok {
+				elements = append(elements, listText(element, text))
+			} else if child, ok := decodeElement(child); ok {
+				elements = append(elements, child)
+			}
+		}
+	default:
+		if child, ok := decodeElement(v); ok {
+			elements = []Element{child}
+		}
+	}
+
+	// Les listes imbriquées prennent les puces du niveau suivant
+	for i := range elements {
+		if elements[i].Type == "list" && elements[i].listLevel == 0 {
+			elements[i].listLevel = element.listLevel + 1
+		}
+	}
+	return elements
+}
+
+// listText construit le texte d'un élément de liste, au style et à l'interligne de la liste
+func listText(element Element, text string) Element {
+	blocks := newBlockStyles(element.Style)
+	style := blocks.withSize(1)
+	style.Height = blocks.lineHeight
+	return Element{Type: "text", Spans: []TextSpan{{Text: text}}, Style: style}
+}
+
+// decodeElement convertit un élément JSON générique (map) en Element
+func decodeElement(value interface{}) (Element, bool) {
+	if element, ok := value.(Element); ok {
+		return element, true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return Element{}, false
+	}
+	var element Element
+	if err := json.Unmarshal(data, &element); err != nil || element.Type == "" {
+		return Element{}, false
+	}
+	return element, true
+}
+
+// markerElement construit le marqueur d'un élément, aligné à droite dans le retrait et
+// centré sur la première ligne du contenu
+func markerElement(element Element, marker string) Element {
+	blocks := newBlockStyles(element.Style)
+	style := blocks.withSize(1)
+	style.Height = blocks.lineHeight
+	style.Align = "right"
+	style.FirstLineIndent = 0
+	return Element{Type: "text", Content: marker, Style: style}
+}
+
+// listItemGap retourne l'espace entre deux éléments de liste
+func listItemGap(element Element) float64 {
+	return newBlockStyles(element.Style).gap / 2
+}
+
+// layoutList place le marqueur puis le contenu de chaque élément ; le marqueur suit la
+// première boîte du contenu (page et ligne)
+func (b *PDFBuilder) layoutList(s *layoutState, element Element, x, width float64) {
+	contentWidth, leftOffset := b.getContentArea(width, element.Style)
+	x += leftOffset
+	indent := b.listIndentOf(element)
+	gap := listItemGap(element)
+
+	for n, item := range element.Items {
+		if n > 0 {
+			s.y += gap
+		}
+
+		start := len(s.boxes)
+		b.withArea(x+indent, contentWidth-indent, func() {
+			b.layoutElements(s, listItemElements(element, item))
+		})
+		if start == len(s.boxes) {
+			continue
+		}
+
+		first := s.boxes[start]
+		marker := markerElement(element, listMarker(element, n))
+		markerBox := Box{
+			Element: marker, Page: first.Page, X: x, Y: first.Y, Width: indent - listMarkerGap, Height: marker.Style.Height,
+		}
+		s.boxes = append(s.boxes[:start], append([]Box{markerBox}, s.boxes[start:]...)...)
+	}
+}
+
+// renderList dessine une liste à la position courante (enfant de grille ou de cadre)
+func (b *PDFBuilder) renderList(element Element) {
+	contentWidth, leftOffset := b.getContentArea(b.area.width, element.Style)
+	x := b.area.x + leftOffset
+	indent := b.listIndentOf(element)
+	gap := listItemGap(element)
+
+	for n, item := range element.Items {
+		if n > 0 {
+			b.pdf.SetY(b.pdf.GetY() + gap)
+		}
+		children := listItemElements(element, item)
+		if len(children) == 0 {
+			continue
+		}
+
+		// Le marqueur est aligné sur le haut du premier élément, marge comprise
+		y := b.pdf.GetY()
+		markerY := y
+		if style := children[0].Style; style != nil && len(style.Margin) > 0 {
+			markerY += parseSpacing(style.Margin).Top
+		}
+
+		b.pdf.SetXY(x, markerY)
+		b.withArea(x, indent-listMarkerGap, func() {
+			b.renderText(markerElement(element, listMarker(element, n)))
+		})
+
+		b.pdf.SetXY(x+indent, y)
+		b.withArea(x+indent, contentWidth-indent, func() {
+			for _, child := range children {
+				b.pdf.SetX(b.area.x)
+				b.renderElement(child)
+			}
+		})
+	}
+
+	b.pdf.SetX(b.area.x)
+}
+
+func (b *PDFBuilder) measureList(element Element, width float64) float64 {
+	contentWidth, _ := b.getContentArea(width, element.Style)
+	innerWidth := contentWidth - b.listIndentOf(element)
+
+	height := 0.0
+	for n, item := range element.Items {
+		if n > 0 {
+			height += listItemGap(element)
+		}
+		for _, child := range listItemElements(element, item) {
+			height += b.measureElement(child, innerWidth)
+		}
+	}
+	return height
+}
@@ -0,0 +1,80 @@
+package template
+
+import "testing"
+
+func TestFormatListNumber(t *testing.T) {
+	tests := []struct {
+		format string
+		number int
+		want   string
+	}{
+		{"1.", 3, "3."},
+		{"a)", 1, "a)"},
+		{"a)", 26, "z)"},
+		{"a)", 27, "aa)"},
+		{"a)", 52, "az)"},
+		{"a)", 53, "ba)"},
+		{"a)", 702, "zz)"},
+		{"a)", 703, "aaa)"},
+		{"A.", 28, "AB."},
+		{"i.", 4, "iv."},
+		{"I.", 9, "IX."},
+		{"(1)", 12, "(12)"},
+		{"§ ", 5, "§ 5"},  // sans compteur : numéro ajouté
+		{"1a.", 2, "2a."}, // seul le premier compteur est remplacé
+	}
+	for _, test := range tests {
+		if got := formatListNumber(test.format, test.number); got != test.want {
+			t.Errorf("%q, %d : %q, attendu %q", test.format, test.number, got, test.want)
+		}
+	}
+}
+
+func TestRomanNumber(t *testing.T) {
+	tests := []struct {
+		number int
+		want   string
+	}{
+		{1, "I"},
+		{3, "III"},
+		{4, "IV"},
+		{9, "IX"},
+		{14, "XIV"},
+		{40, "XL"},
+		{90, "XC"},
+		{400, "CD"},
+		{900, "CM"},
+		{1994, "MCMXCIV"},
+		{3999, "MMMCMXCIX"},
+		{0, "0"},       // hors de la plage : chiffres arabes
+		{4000, "4000"}, // idem
+	}
+	for _, test := range tests {
+		if got := romanNumber(test.number); got != test.want {
+			t.Errorf("%d : %q, attendu %q", test.number, got, test.want)
+		}
+	}
+}
+
+func TestAlphaNumber(t *testing.T) {
+	tests := []struct {
+		number int
+		want   string
+	}{
+		{1, "a"},
+		{26, "z"},
+		{27, "aa"},
+		{28, "ab"},
+		{52, "az"},
+		{53, "ba"},
+		{702, "zz"},
+		{703, "aaa"},
+		{0, "0"},
+		{-2, "-2"},
+	}
+	for _, test := range tests {
+		if got := alphaNumber(test.number); got != test.want {
+			t.Errorf("%d : %q, attendu %q", test.number, got, test.want)
+		}
+	}
+}
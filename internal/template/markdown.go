@@ -0,0 +1,324 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+)
+
+// --- Contenu Markdown ---
+//
+// Un élément markdown est converti en éléments existants (textes riches, tableaux,
+// lignes) puis mis en page comme une suite d'éléments du flux. Syntaxe prise en
+// charge : titres (#), paragraphes, **gras**, *italique*, `code`, [liens](url),
+// listes à puces et numérotées (imbriquées par indentation), citations (>),
+// blocs de code (```), séparateurs (---) et tableaux simples (| a | b |).
+
+// markdownCodeFont est la police des extraits de code
+const markdownCodeFont = "Courier"
+
+var (
+	mdHeadingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdBulletRe  = regexp.MustCompile(`^(\s*)([-*+])\s+(.*)$`)
+	mdOrderedRe = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	mdRuleRe    = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	mdTableSep  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// markdownElements convertit le contenu d'un élément markdown en éléments, pour la largeur donnée
+func markdownElements(element Element, width float64) []Element {
+	blocks := newBlockStyles(element.Style)
+	base, lineHeight, gap := blocks.base, blocks.lineHeight, blocks.gap
+
+	var elements []Element
+	lines := strings.Split(strings.ReplaceAll(textContent(element), "\r", ""), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			continue
+
+		case strings.HasPrefix(trimmed, "```"):
+			// Bloc de code : lignes conservées telles quelles
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			style := mergeStyle(base, &Style{Font: markdownCodeFont})
+			style.Size = blocks.size * 0.9
+			style.Height = lineHeight
+			style.BgColor, style.Fill = "#F4F4F4", true
+			style.Margin = []float64{0, 0, gap, 0}
+			elements = append(elements, Element{Type: "text", Content: strings.Join(code, "\n") + "\n", Style: style})
+
+		case mdHeadingRe.MatchString(trimmed):
+			match := mdHeadingRe.FindStringSubmatch(trimmed)
+			elements = append(elements, blocks.heading(len(match[1]), parseInline(match[2], nil), len(elements) == 0))
+
+		case mdRuleRe.MatchString(trimmed):
+			elements = append(elements, Element{Type: "line", Style: &Style{Color: "#999999", Margin: []float64{gap / 2, 0, gap, 0}}})
+
+		case i+1 < len(lines) && strings.Contains(trimmed, "|") && mdTableSep.MatchString(lines[i+1]):
+			end := i + 2
+			for end < len(lines) && strings.Contains(lines[end], "|") && strings.TrimSpace(lines[end]) != "" {
+				end++
+			}
+			elements = append(elements, markdownTable(lines[i:end], base, width, gap))
+			i = end - 1
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			i--
+			elements = append(elements, blocks.quote(parseInline(joinLines(quote), nil)))
+
+		case mdBulletRe.MatchString(line) || mdOrderedRe.MatchString(line):
+			end := i
+			for end < len(lines) && strings.TrimSpace(lines[end]) != "" {
+				end++
+			}
+			for _, item := range markdownListItems(lines[i:end]) {
+				elements = append(elements, blocks.listItem(item.marker, item.level, parseInline(item.text, nil)))
+			}
+			blocks.endList(elements)
+			i = end - 1
+
+		default:
+			// Paragraphe : lignes consécutives jusqu'à une ligne vide ou un autre bloc
+			var text []string
+			for ; i < len(lines); i++ {
+				current := strings.TrimSpace(lines[i])
+				if current == "" || (len(text) > 0 && startsBlock(lines, i)) {
+					break
+				}
+				// Deux espaces ou une barre oblique inverse en fin de ligne : retour à la ligne forcé
+				if strings.HasSuffix(lines[i], "  ") || strings.HasSuffix(current, "\\") {
+					current = strings.TrimSuffix(current, "\\") + "\n"
+				}
+				text = append(text, current)
+			}
+			i--
+			elements = append(elements, blocks.paragraph(parseInline(joinLines(text), nil), blocks.withSize(1), 0))
+		}
+	}
+
+	blocks.end(elements)
+	return elements
+}
+
+// startsBlock indique si la ligne i commence un nouveau bloc (et termine donc un paragraphe)
+func startsBlock(lines []string, i int) bool {
+	trimmed := strings.TrimSpace(lines[i])
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, ">") ||
+		mdHeadingRe.MatchString(trimmed) || mdRuleRe.MatchString(trimmed) ||
+		mdBulletRe.MatchString(lines[i]) || mdOrderedRe.MatchString(lines[i])
+}
+
+// joinLines assemble les lignes d'un paragraphe (les retours forcés sont conservés)
+func joinLines(lines []string) string {
+	var sb strings.Builder
+	for i, line := range lines {
+		sb.WriteString(line)
+		if i < len(lines)-1 && !strings.HasSuffix(line, "\n") {
+			sb.WriteString(" ")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// markdownItem est un élément de liste markdown
+type markdownItem struct {
+	level  int
+	marker string
+	text   string
+}
+
+// markdownListItems lit une liste : le niveau dépend de l'indentation, les lignes sans marqueur
+// prolongent l'élément précédent
+func markdownListItems(lines []string) []markdownItem {
+	var items []markdownItem
+	var indents []int // indentation de chaque niveau ouvert
+
+	for _, line := range lines {
+		indent, marker, text := 0, "", ""
+		if match := mdBulletRe.FindStringSubmatch(line); match != nil {
+			indent, marker, text = len(match[1]), "•", match[3]
+		} else if match := mdOrderedRe.FindStringSubmatch(line); match != nil {
+			indent, marker, text = len(match[1]), match[2]+".", match[3]
+		} else if len(items) > 0 {
+			items[len(items)-1].text += " " + strings.TrimSpace(line)
+			continue
+		} else {
+			continue
+		}
+
+		for len(indents) > 0 && indent < indents[len(indents)-1] {
+			indents = indents[:len(indents)-1]
+		}
+		if len(indents) == 0 || indent > indents[len(indents)-1] {
+			indents = append(indents, indent)
+		}
+
+		items = append(items, markdownItem{level: len(indents) - 1, marker: marker, text: text})
+	}
+	return items
+}
+
+// markdownTable convertit un tableau markdown (en-têtes, séparateur, lignes) en élément table
+// occupant toute la largeur, colonnes de largeur égale
+func markdownTable(lines []string, base *Style, width, gap float64) Element {
+	headers := splitTableRow(lines[0])
+	separators := splitTableRow(lines[1])
+
+	columns := make([]TableColumn, len(headers))
+	for c, header := range headers {
+		columns[c] = TableColumn{Header: plainInline(header), Width: width / float64(len(headers))}
+		if c < len(separators) {
+			sep := separators[c]
+			switch {
+			case strings.HasPrefix(sep, ":") && strings.HasSuffix(sep, ":"):
+				columns[c].Align = "center"
+			case strings.HasSuffix(sep, ":"):
+				columns[c].Align = "right"
+			}
+		}
+	}
+
+	rowStyle := mergeStyle(base, nil)
+	var rows []TableRow
+	for _, line := range lines[2:] {
+		cells := splitTableRow(line)
+		row := TableRow{Cells: make([]string, len(columns)), Style: rowStyle}
+		for c := range columns {
+			if c < len(cells) {
+				row.Cells[c] = plainInline(cells[c])
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	headerStyle := mergeStyle(base, &Style{Bold: true})
+	headerStyle.Margin = []float64{0, 0, gap, 0}
+	return Element{Type: "table", Columns: columns, Rows: rows, Style: headerStyle}
+}
+
+// splitTableRow découpe une ligne de tableau markdown en cellules
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// parseInline convertit le balisage en ligne (gras, italique, code, liens) en spans ;
+// style porte le style partiel hérité (emphase imbriquée)
+func parseInline(text string, style *Style) []TextSpan {
+	var spans []TextSpan
+	var plain strings.Builder
+
+	current := func() *Style {
+		if style == nil {
+			return nil
+		}
+		copied := *style
+		return &copied
+	}
+	flush := func() {
+		if plain.Len() > 0 {
+			spans = append(spans, TextSpan{Text: plain.String(), Style: current()})
+			plain.Reset()
+		}
+	}
+	emphasis := func(bold, italic bool) *Style {
+		s := mergeStyle(style, nil)
+		s.Bold = s.Bold || bold
+		s.Italic = s.Italic || italic
+		return s
+	}
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_[]()#+-.!|>", rune(rest[1])):
+			plain.WriteByte(rest[1])
+			i += 2
+			continue
+
+		case rest[0] == '`':
+			if end := strings.Index(rest[1:], "`"); end >= 0 {
+				flush()
+				code := mergeStyle(style, &Style{Font: markdownCodeFont})
+				spans = append(spans, TextSpan{Text: rest[1 : end+1], Style: code})
+				i += end + 2
+				continue
+			}
+
+		case strings.HasPrefix(rest, "***") || strings.HasPrefix(rest, "___"):
+			if end := strings.Index(rest[3:], rest[:3]); end > 0 {
+				flush()
+				spans = append(spans, parseInline(rest[3:end+3], emphasis(true, true))...)
+				i += end + 6
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				flush()
+				spans = append(spans, parseInline(rest[2:end+2], emphasis(true, false))...)
+				i += end + 4
+				continue
+			}
+
+		case rest[0] == '*' || (rest[0] == '_' && (i == 0 || !isWordByte(text[i-1]))):
+			if end := strings.IndexByte(rest[1:], rest[0]); end > 0 && rest[1] != ' ' {
+				flush()
+				spans = append(spans, parseInline(rest[1:end+1], emphasis(false, true))...)
+				i += end + 2
+				continue
+			}
+
+		case rest[0] == '[':
+			if mid := strings.Index(rest, "]("); mid > 0 {
+				if end := strings.IndexByte(rest[mid:], ')'); end > 0 {
+					flush()
+					url := strings.TrimSpace(rest[mid+2 : mid+end])
+					for _, span := range parseInline(rest[1:mid], style) {
+						span.Link = url
+						spans = append(spans, span)
+					}
+					i += mid + end + 1
+					continue
+				}
+			}
+		}
+
+		plain.WriteByte(rest[0])
+		i++
+	}
+
+	flush()
+	return spans
+}
+
+// plainInline retire le balisage en ligne d'un texte (cellules de tableau)
+func plainInline(text string) string {
+	var sb strings.Builder
+	for _, span := range parseInline(text, nil) {
+		sb.WriteString(span.Text)
+	}
+	return sb.String()
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,35 @@
+package template
+
+import (
+	"encoding/base64"
+	"os"
+	"testing"
+)
+
+func TestMarkdownMissingVariant(t *testing.T) {
+	// Police personnalisée sans variante grasse ni italique : les titres, le gras et les citations
+	// produisent une erreur, pas de panique ; le texte courant reste rendu
+	data, err := os.ReadFile("fontpack/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		content string
+		fails   bool
+	}{
+		{"# Titre", true},
+		{"Texte **gras**", true},
+		{"> Citation", true},
+		{"Texte courant\n\n- élément", false},
+	}
+	for _, test := range tests {
+		template := Template{
+			Fonts:    FontConfig{Default: "Custom", Base64Data: map[string]string{"Custom": base64.StdEncoding.EncodeToString(data)}},
+			Elements: []Element{{Type: "markdown", Content: test.content}},
+		}
+		_, err := NewPDFBuilder(template).Build()
+		if (err != nil) != test.fails {
+			t.Errorf("%q : erreur %v, attendu une erreur : %v", test.content, err, test.fails)
+		}
+	}
+}
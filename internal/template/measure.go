@@ -0,0 +1,240 @@
+package template
+
+import (
+	"math"
+	"strings"
+)
+
+// --- Mesure des éléments sans rendu ---
+//
+// Les fonctions measure* reproduisent les calculs de hauteur des fonctions render*
+// correspondantes sans émettre d'opération de dessin : seule la police peut être
+// sélectionnée pour mesurer le texte, puis elle est restaurée.
+
+// measureElement calcule la hauteur qu'occupe un élément rendu par renderElement dans la largeur donnée
+func (b *PDFBuilder) measureElement(element Element, width float64) float64 {
+	// Un élément positionné n'occupe pas de place dans le flux
+	if element.Position != nil {
+		return 0
+	}
+
+	height := 0.0
+
+	var margin Spacing
+	if element.Style != nil && len(element.Style.Margin) > 0 {
+		margin = parseSpacing(element.Style.Margin)
+	}
+	if margin.Top > 0 {
+		height += margin.Top
+	}
+
+	height += b.measureContent(element, width)
+
+	if margin.Bottom > 0 {
+		height += margin.Bottom
+	}
+	return height
+}
+
+// measureContent calcule la hauteur d'un élément hors marges verticales
+func (b *PDFBuilder) measureContent(element Element, width float64) float64 {
+	switch element.Type {
+	case "text":
+		return b.measureText(element, width)
+	case "table":
+		return b.measureTable(element)
+	case "grid":
+		return b.measureGrid(element, width)
+	case "space":
+		return b.measureSpace(element)
+	case "line":
+		return 2
+	case "image":
+		return b.measureImage(element, width)
+	case "box":
+		return b.measureBox(element, width)
+	case "list":
+		return b.measureList(element, width)
+	case "markdown", "html":
+		return b.measureBlocks(element, width)
+	}
+	return 0
+}
+
+// measureElementInWidth est l'équivalent de renderElementInWidth (enfant de grille)
+func (b *PDFBuilder) measureElementInWidth(element Element, width float64) float64 {
+	if element.Type == "text" {
+		return b.measureTextInWidth(element, width)
+	}
+	return b.measureElement(element, width)
+}
+
+func (b *PDFBuilder) measureText(element Element, width float64) float64 {
+	contentWidth, _ := b.getContentArea(width, element.Style)
+	element = b.fitText(element, contentWidth)
+	height := textLineHeight(element.Style, 8)
+
+	if b.richLayout(element) {
+		return float64(len(b.richLines(element, contentWidth))) * height
+	}
+
+	if noWrap(element.Style) {
+		return height
+	}
+	return float64(b.countLines(element.Style, textContent(element), contentWidth)) * height
+}
+
+func (b *PDFBuilder) measureTextInWidth(element Element, width float64) float64 {
+	element = b.fitText(element, width)
+	height := textLineHeight(element.Style, 5)
+
+	if b.richLayout(element) {
+		lines := len(b.richLines(element, width))
+		if lines == 1 && (element.Style == nil || element.Style.LineHeight == 0) {
+			return height * 1.5
+		}
+		return float64(lines) * height
+	}
+
+	if noWrap(element.Style) {
+		return height * 1.5
+	}
+
+	content := textContent(element)
+	lines := b.countLines(element.Style, content, width)
+	if lines == 1 && !strings.Contains(content, "\n") {
+		return height * 1.5
+	}
+	return float64(lines) * height
+}
+
+func (b *PDFBuilder) measureTable(element Element) float64 {
+	if len(element.Columns) == 0 {
+		return 0
+	}
+
+	rows := b.tableRows(element)
+	if len(rows) == 0 {
+		return 0
+	}
+
+	// En-têtes + lignes, sans tenir compte des sauts de page ; la hauteur des lignes ne dépend pas
+	// de la largeur (colonnes réduites à la zone par fitColumns)
+	return float64(len(rows)+1) * tableRowHeight
+}
+
+func (b *PDFBuilder) measureGrid(element Element, width float64) float64 {
+	layout, ok := newGridLayout(element, width)
+	if !ok {
+		return 0
+	}
+
+	height := 0.0
+	for _, row := range layout.rows {
+		_, rowHeight := b.measureGridRow(layout, row)
+		height += rowHeight + layout.rowGap
+	}
+	return height
+}
+
+func (b *PDFBuilder) measureSpace(element Element) float64 {
+	if element.Style != nil && element.Style.Height > 0 {
+		return element.Style.Height
+	}
+	return 5.0
+}
+
+func (b *PDFBuilder) measureImage(element Element, width float64) float64 {
+	if _, _, ok := decodeImageData(element.Content); !ok {
+		return 0
+	}
+
+	imageWidth, height := imageDimensions(element.Style, width)
+	if height == 0 {
+		height = imageWidth * 0.75 // ratio par défaut
+	}
+	return height + 2
+}
+
+// countLines compte les lignes produites par MultiCell pour un texte dans la police du style,
+// puis restaure la police courante
+func (b *PDFBuilder) countLines(style *Style, content string, width float64) int {
+	saved := b.font
+	b.applyFont(style)
+	lines := b.wrapLines(b.encode(content), width)
+	if saved.family != "" {
+		b.setFont(saved.family, saved.style, saved.size)
+	}
+	return len(lines)
+}
+
+// wrapLines découpe un texte avec la police courante en suivant l'algorithme de MultiCell :
+// coupure au dernier espace lorsque la largeur est dépassée, ou au caractère à défaut
+func (b *PDFBuilder) wrapLines(text string, width float64) []string {
+	_, fontSize := b.pdf.GetFontSize()
+	if fontSize <= 0 {
+		return []string{text}
+	}
+	wmax := int(math.Ceil((width - 2*b.pdf.GetCellMargin()) * 1000 / fontSize))
+
+	// Unités de mesure : runes pour les polices UTF-8, octets pour les polices standard
+	text = strings.ReplaceAll(text, "\r", "")
+	var units []string
+	if b.utf8Fonts[strings.ToLower(b.font.family)] {
+		for _, r := range text {
+			units = append(units, string(r))
+		}
+		for len(units) > 0 && units[len(units)-1] == "\n" {
+			units = units[:len(units)-1]
+		}
+	} else {
+		for i := 0; i < len(text); i++ {
+			units = append(units, text[i:i+1])
+		}
+		if len(units) > 0 && units[len(units)-1] == "\n" {
+			units = units[:len(units)-1]
+		}
+	}
+
+	var lines []string
+	sep, i, j, l := -1, 0, 0, 0
+	for i < len(units) {
+		c := units[i]
+		if c == "\n" {
+			lines = append(lines, strings.Join(units[j:i], ""))
+			i++
+			sep, j, l = -1, i, 0
+			continue
+		}
+		if c == " " {
+			sep = i
+		}
+
+		l += b.pdf.GetStringSymbolWidth(c)
+		if l > wmax {
+			if sep == -1 {
+				if i == j {
+					i++
+				}
+				lines = append(lines, strings.Join(units[j:i], ""))
+			} else {
+				lines = append(lines, strings.Join(units[j:sep], ""))
+				i = sep + 1
+			}
+			sep, j, l = -1, i, 0
+		} else {
+			i++
+		}
+	}
+
+	// Dernier morceau (toujours émis par MultiCell, même vide)
+	return append(lines, strings.Join(units[j:i], ""))
+}
+
+// textContent retourne le contenu texte d'un élément
+func textContent(element Element) string {
+	if str, ok := element.Content.(string); ok {
+		return str
+	}
+	return ""
+}
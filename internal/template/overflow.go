@@ -0,0 +1,62 @@
+package template
+
+import "strings"
+
+// --- Texte sur une seule ligne : whiteSpace et overflow ---
+//
+// Un texte simple est réparti en lignes dès qu'il dépasse la largeur disponible.
+// Avec whiteSpace "nowrap" (ou un overflow explicite), il reste sur une ligne et
+// overflow précise le traitement du dépassement : "ellipsis" tronque le texte avec
+// des points de suspension, "clip" le coupe au bord de la cellule et "shrink"
+// réduit la taille de police jusqu'à ce qu'il tienne.
+
+// noWrap indique si un texte doit rester sur une seule ligne
+func noWrap(style *Style) bool {
+	if style == nil {
+		return false
+	}
+	return style.WhiteSpace == "nowrap" || (style.Overflow != "" && style.Overflow != "visible")
+}
+
+// renderSingleLine écrit un texte sur une seule ligne à la position courante, dans une cellule
+// de largeur width ; les retours à la ligne deviennent des espaces
+func (b *PDFBuilder) renderSingleLine(style *Style, content string, width, height float64, border, align string, fill bool) {
+	content = strings.ReplaceAll(content, "\n", " ")
+	overflow, direction := "", ""
+	if style != nil {
+		overflow, direction = style.Overflow, style.Direction
+	}
+
+	text := b.visualText(content, direction)
+	available := width - 2*b.pdf.GetCellMargin()
+	overflows := b.pdf.GetStringWidth(text) > available
+
+	switch {
+	case overflow == "ellipsis" && overflows && b.bidiApplies(content, direction):
+		// Troncature dans l'ordre logique : la fin du texte disparaît, quel que soit son sens
+		head := b.fitPrefix(shapeArabic(content), available-b.pdf.GetStringWidth("…"), b.pdf.GetStringWidth)
+		text = b.visualText(strings.TrimRight(head, " ")+"…", direction)
+
+	case overflow == "ellipsis" && overflows:
+		ellipsis := b.encode("…")
+		head := b.fitPrefix(text, available-b.pdf.GetStringWidth(ellipsis), b.pdf.GetStringWidth)
+		text = strings.TrimRight(head, " ") + ellipsis
+
+	case overflow == "shrink" && overflows:
+		// Taille proportionnelle au dépassement, la police est restaurée après l'écriture
+		saved := b.font
+		b.setFont(saved.family, saved.style, saved.size*available/b.pdf.GetStringWidth(text))
+		defer b.setFont(saved.family, saved.style, saved.size)
+
+	case overflow == "clip" && overflows:
+		b.pdf.ClipRect(b.pdf.GetX(), b.pdf.GetY(), width, height, false)
+		defer b.pdf.ClipEnd()
+	}
+
+	// Texte tronqué : déjà encodé ; sinon écrit avec les éventuelles polices de secours
+	if overflow == "ellipsis" && overflows {
+		b.pdf.CellFormat(width, height, text, border, 1, align, fill, 0, "")
+		return
+	}
+	b.drawTextCell(width, height, content, direction, border, 1, align, fill)
+}
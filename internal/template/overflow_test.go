@@ -0,0 +1,68 @@
+package template
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fontSizesRe reconnaît les changements de taille de police d'un flux de contenu
+var fontSizesRe = regexp.MustCompile(`/F\w+ ([\d.]+) Tf`)
+
+func TestRenderSingleLine(t *testing.T) {
+	long := "Un texte beaucoup trop long pour la cellule"
+	tests := []struct {
+		name     string
+		overflow string
+		content  string
+		text     string // texte écrit
+		clip     bool   // zone de découpe
+		shrunk   bool   // police réduite le temps de l'écriture
+	}{
+		{"ellipsis", "ellipsis", long, "Un texte beauc\x85", false, false}, // … en cp1252
+		{"ellipsis sans dépassement", "ellipsis", "Court", "Court", false, false},
+		{"clip", "clip", long, long, true, false},
+		{"clip sans dépassement", "clip", "Court", "Court", false, false},
+		{"shrink", "shrink", long, long, false, true},
+		{"shrink sans dépassement", "shrink", "Court", "Court", false, false},
+		{"nowrap", "", long, long, false, false},
+		{"retours à la ligne", "ellipsis", "un\ndeux", "un deux", false, false},
+	}
+	for _, test := range tests {
+		style := &Style{WhiteSpace: "nowrap", Overflow: test.overflow}
+		builder := NewPDFBuilder(Template{})
+		builder.pdf.SetCompression(false)
+		builder.pdf.AddPage()
+		builder.applyStyle(style)
+		builder.pdf.SetXY(20, 20)
+		builder.renderSingleLine(style, test.content, 30, 8, "", "L", false)
+		if builder.font.size != 10 {
+			t.Errorf("%s : police de %v pt après l'écriture, attendu 10 pt", test.name, builder.font.size)
+		}
+
+		var pdf bytes.Buffer
+		if err := builder.pdf.Output(&pdf); err != nil {
+			t.Fatal(err)
+		}
+		content := pageStreams(t, pdf.Bytes(), 1)[0]
+
+		if !strings.Contains(content, "("+test.text+")Tj") {
+			t.Errorf("%s : texte %q absent de %q", test.name, test.text, content)
+		}
+		if clip := strings.Contains(content, " re W n"); clip != test.clip {
+			t.Errorf("%s : découpe %v, attendu %v", test.name, clip, test.clip)
+		}
+		sizes := fontSizesRe.FindAllStringSubmatch(content, -1)
+		shrunk := false
+		for _, size := range sizes {
+			if v, _ := strconv.ParseFloat(size[1], 64); v < 10 {
+				shrunk = true
+			}
+		}
+		if shrunk != test.shrunk || sizes[len(sizes)-1][1] != "10.00" {
+			t.Errorf("%s : tailles de police %q, réduction attendue : %v", test.name, sizes, test.shrunk)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package template
+
+// --- Positionnement absolu ---
+//
+// Un élément qui définit position est dessiné à des coordonnées fixes de la page
+// (adresse pour enveloppe à fenêtre, logo, tampon) : il ne déplace pas le curseur
+// du flux et n'occupe pas de place dans les grilles ni dans les cadres.
+
+// positionedArea retourne le coin supérieur gauche et la largeur d'un élément positionné.
+// Sans style.width, l'élément s'étend jusqu'à la marge droite.
+func (b *PDFBuilder) positionedArea(element Element) (x, y, width float64) {
+	pos := element.Position
+	x, y = pos.X, pos.Y
+	if pos.Anchor == "margin" {
+		x += b.margins.left
+		y += b.margins.top
+	}
+
+	pageWidth, _ := b.pdf.GetPageSize()
+	if element.Style != nil && element.Style.Width > 0 {
+		width = element.Style.Width
+	} else {
+		width = pageWidth - b.margins.right - x
+		if width <= 0 {
+			width = pageWidth - x
+		}
+	}
+	return x, y, width
+}
+
+// layoutPositioned ajoute la boîte d'un élément positionné sur la page courante, sans avancer le curseur
+func (b *PDFBuilder) layoutPositioned(s *layoutState, element Element) {
+	x, y, width := b.positionedArea(element)
+	s.boxes = append(s.boxes, Box{
+		Element: element, Page: s.page, X: x, Y: y, Width: width, Height: b.measureContent(element, width),
+	})
+}
+
+// renderPositioned dessine un élément positionné puis restaure la position courante du flux
+func (b *PDFBuilder) renderPositioned(element Element) {
+	savedX, savedY := b.pdf.GetXY()
+	x, y, width := b.positionedArea(element)
+
+	element.Position = nil
+	b.pdf.SetXY(x, y)
+	b.withArea(x, width, func() {
+		b.renderElement(element)
+	})
+
+	b.pdf.SetXY(savedX, savedY)
+}
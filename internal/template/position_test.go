@@ -0,0 +1,56 @@
+package template
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPositionedArea(t *testing.T) {
+	// Page A4 (210 mm, au centième près) avec les marges [20, 10, 30, 20]
+	template := Template{}
+	template.Page.Margins = []float64{20, 10, 30, 20}
+	builder := NewPDFBuilder(template)
+
+	positioned := func(x, y float64, anchor string, style *Style) Element {
+		return Element{Type: "text", Content: "Tampon", Position: &Position{X: x, Y: y, Anchor: anchor}, Style: style}
+	}
+	tests := []struct {
+		name        string
+		element     Element
+		x, y, width float64
+	}{
+		{"page par défaut", positioned(100, 40, "", nil), 100, 40, 80},
+		{"page", positioned(100, 40, "page", nil), 100, 40, 80},
+		{"alias top-left", positioned(100, 40, "top-left", nil), 100, 40, 80},
+		{"marge", positioned(100, 40, "margin", nil), 120, 50, 60},
+		{"largeur imposée", positioned(100, 40, "page", &Style{Width: 50}), 100, 40, 50},
+		{"largeur imposée depuis la marge", positioned(100, 40, "margin", &Style{Width: 100}), 120, 50, 100},
+		{"au-delà de la marge droite", positioned(190, 0, "page", nil), 190, 0, 20},
+		{"au-delà de la marge droite depuis la marge", positioned(170, 0, "margin", nil), 190, 10, 20},
+	}
+	for _, test := range tests {
+		x, y, width := builder.positionedArea(test.element)
+		if x != test.x || y != test.y || math.Abs(width-test.width) > 0.01 {
+			t.Errorf("%s : (%v, %v) sur %v mm, attendu (%v, %v) sur %v mm", test.name, x, y, width, test.x, test.y, test.width)
+		}
+	}
+}
+
+func TestPositionedOutOfFlow(t *testing.T) {
+	// Un élément positionné n'avance pas le flux et garde ses coordonnées
+	template := Template{Elements: []Element{
+		{Type: "text", Content: "Adresse", Position: &Position{X: 110, Y: 45, Anchor: "margin"}},
+		{Type: "text", Content: "Corps"},
+	}}
+	builder := NewPDFBuilder(template)
+	boxes := builder.Layout()
+	if len(boxes) != 2 {
+		t.Fatalf("%d boîte(s), attendu 2", len(boxes))
+	}
+	if address := boxes[0]; address.X != 125 || address.Y != 57 || math.Abs(address.Width-70) > 0.01 {
+		t.Errorf("adresse en (%v, %v) sur %v mm, attendu (125, 57) sur 70 mm", address.X, address.Y, address.Width)
+	}
+	if body := boxes[1]; body.Y != builder.margins.top {
+		t.Errorf("corps à %v mm, attendu en haut de la zone de contenu (%v mm)", body.Y, builder.margins.top)
+	}
+}
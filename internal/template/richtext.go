@@ -0,0 +1,452 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// --- Texte riche : spans avec styles partiels et liens ---
+//
+// Les spans d'un élément texte sont découpés en mots, répartis en lignes avec la
+// police de chaque span, puis chaque morceau de ligne est écrit avec Write /
+// WriteLinkString. Le découpage étant calculé par nos soins, la mesure des
+// hauteurs (mise en page, grilles) correspond exactement au rendu.
+//
+// Les textes simples passent par le même moteur dès qu'ils utilisent la
+// justification, l'interligne, l'espacement des lettres ou des mots, le
+// retrait de première ligne ou l'écriture de droite à gauche, que MultiCell et
+// CellFormat ne savent pas rendre.
+
+// linkColor est la couleur par défaut des liens sans couleur explicite
+const linkColor = "#0645AD"
+
+// textRun est un morceau de texte avec son style complet (style de l'élément fusionné)
+type textRun struct {
+	text  string
+	style *Style
+	link  string
+}
+
+// richFragment est la partie d'un run placée sur une ligne (texte déjà traduit)
+type richFragment struct {
+	run   int
+	text  string
+	width float64
+	size  float64 // taille de police en mm, pour aligner les lignes de base
+	space bool    // espace entre deux mots (supprimé en fin de ligne)
+}
+
+// richLine est une ligne de texte riche
+type richLine struct {
+	fragments []richFragment
+	width     float64
+	maxSize   float64 // plus grande taille de police de la ligne, en mm
+	indent    float64 // retrait de la ligne (première ligne d'un paragraphe), en mm
+	end       bool    // dernière ligne d'un paragraphe (jamais justifiée)
+	rtl       bool    // ligne d'un paragraphe de droite à gauche
+}
+
+// richLayout indique si un texte est réparti en lignes par wrapRuns plutôt que par
+// MultiCell / CellFormat : texte riche, propriétés typographiques, césure, texte de droite à gauche
+// ou caractères à écrire avec une police de secours (hors texte sur une ligne)
+func (b *PDFBuilder) richLayout(element Element) bool {
+	if len(element.Spans) > 0 {
+		return true
+	}
+	style := element.Style
+	if noWrap(style) {
+		return false
+	}
+	if content := textContent(element); containsRTL(content) || b.needsFallback(b.styleFamily(style), content) {
+		return true
+	}
+	return style != nil && (style.Align == "justify" || style.LineHeight > 0 || style.LetterSpacing != 0 ||
+		style.WordSpacing != 0 || style.FirstLineIndent != 0 || style.Hyphenate || style.Direction == "rtl")
+}
+
+// textLineHeight retourne l'interligne d'un texte en mm : lineHeight × taille de police
+// s'il est précisé, sinon style.height, sinon la valeur par défaut
+func textLineHeight(style *Style, fallback float64) float64 {
+	if style == nil {
+		return fallback
+	}
+	if style.LineHeight > 0 {
+		size := 10.0
+		if style.Size > 0 {
+			size = style.Size
+		}
+		return size * 0.3528 * style.LineHeight // pt -> mm
+	}
+	if style.Height > 0 {
+		return style.Height
+	}
+	return fallback
+}
+
+// textAlign convertit l'alignement d'un texte en alignement gofpdf ("J" : justifié) ; un texte
+// de droite à gauche est aligné à droite par défaut
+func textAlign(style *Style) string {
+	if style != nil && style.Align == "justify" {
+		return "J"
+	}
+	if style == nil {
+		return "L"
+	}
+	return directionAlign(style.Align, style.Direction)
+}
+
+// textRuns construit les runs des spans d'un élément (un texte simple forme un seul run),
+// découpés selon les polices de secours
+func (b *PDFBuilder) textRuns(element Element) []textRun {
+	if len(element.Spans) == 0 {
+		return b.fallbackRuns([]textRun{{text: textContent(element), style: mergeStyle(element.Style, nil)}})
+	}
+
+	runs := make([]textRun, 0, len(element.Spans))
+	for _, span := range element.Spans {
+		style := mergeStyle(element.Style, span.Style)
+		if span.Link != "" && (span.Style == nil || span.Style.Color == "") {
+			style.Color = linkColor
+			style.Underline = true
+		}
+		runs = append(runs, textRun{text: span.Text, style: style, link: span.Link})
+	}
+	return b.fallbackRuns(runs)
+}
+
+// mergeStyle applique un style partiel sur un style de base : les valeurs renseignées
+// remplacent celles de la base, gras, italique et souligné s'ajoutent
+func mergeStyle(base, partial *Style) *Style {
+	merged := Style{}
+	if base != nil {
+		merged = *base
+	}
+	if partial == nil {
+		return &merged
+	}
+
+	if partial.Font != "" {
+		merged.Font = partial.Font
+	}
+	if partial.Size > 0 {
+		merged.Size = partial.Size
+	}
+	if partial.Color != "" {
+		merged.Color = partial.Color
+	}
+	if partial.BgColor != "" {
+		merged.BgColor = partial.BgColor
+	}
+	if partial.LetterSpacing != 0 {
+		merged.LetterSpacing = partial.LetterSpacing
+	}
+	if partial.WordSpacing != 0 {
+		merged.WordSpacing = partial.WordSpacing
+	}
+	merged.Bold = merged.Bold || partial.Bold
+	merged.Italic = merged.Italic || partial.Italic
+	merged.Underline = merged.Underline || partial.Underline
+
+	return &merged
+}
+
+// wrapRuns répartit des runs en lignes de largeur maximale width (marges de cellule déduites),
+// en coupant aux espaces, aux points de césure (style.hyphenate), ou au caractère pour un mot
+// plus long qu'une ligne. La première ligne de chaque paragraphe est réduite du retrait indent.
+func (b *PDFBuilder) wrapRuns(runs []textRun, width, indent float64) []richLine {
+	saved := b.font
+	defer func() {
+		if saved.family != "" {
+			b.setFont(saved.family, saved.style, saved.size)
+		}
+	}()
+
+	maxWidth := width - 2*b.pdf.GetCellMargin()
+
+	var lines []richLine
+	line := richLine{indent: indent}
+	paragraph := 0 // indice de la première ligne du paragraphe courant
+
+	pushLine := func(end bool) {
+		// Les espaces de fin de ligne ne comptent pas
+		for len(line.fragments) > 0 && line.fragments[len(line.fragments)-1].space {
+			line.width -= line.fragments[len(line.fragments)-1].width
+			line.fragments = line.fragments[:len(line.fragments)-1]
+		}
+		line.end = end
+		lines = append(lines, line)
+		line = richLine{}
+		if end {
+			line.indent = indent
+			b.setDirection(runs, lines[paragraph:])
+			paragraph = len(lines)
+		}
+	}
+
+	for r, run := range runs {
+		b.applyFont(run.style)
+		_, size := b.pdf.GetFontSize()
+		hyphens := b.runHyphenator(run.style)
+
+		// Les polices UTF-8 reçoivent le texte tel quel, l'arabe mis en forme ; les polices
+		// 8 bits le texte traduit dans leur page de codes
+		family := b.styleFamily(run.style)
+		encode := func(text string) string { return b.encodeFor(family, text) }
+		content := run.text
+		if b.isUTF8Style(run.style) {
+			content = shapeArabic(content)
+		}
+
+		// Largeur d'un texte, espacement des lettres compris
+		measure := func(text string) float64 {
+			return b.pdf.GetStringWidth(text) + run.style.LetterSpacing*float64(b.glyphCount(text))
+		}
+		add := func(text string, width float64, space bool) {
+			if size > line.maxSize {
+				line.maxSize = size
+			}
+			line.fragments = append(line.fragments, richFragment{run: r, text: text, width: width, size: size, space: space})
+			line.width += width
+		}
+
+		for _, token := range splitWords(content) {
+			switch {
+			case token == "\n":
+				if line.maxSize == 0 {
+					line.maxSize = size
+				}
+				pushLine(true)
+			case strings.TrimLeft(token, " ") == "":
+				// Pas d'espace en début de ligne
+				if len(line.fragments) > 0 {
+					text := encode(token)
+					add(text, measure(text)+run.style.WordSpacing*float64(len(token)), true)
+				}
+			default:
+				// Césure : la première partie du mot termine la ligne courante
+				word := token
+				for hyphens != nil {
+					room := maxWidth - line.indent - line.width
+					if measure(encode(word)) <= room {
+						break
+					}
+					if head, rest, ok := hyphens.hyphenate(word, room, encode, measure); ok {
+						text := encode(head)
+						add(text, measure(text), false)
+						pushLine(false)
+						word = rest
+						continue
+					}
+					if len(line.fragments) == 0 {
+						break
+					}
+					pushLine(false)
+				}
+
+				text := encode(word)
+				w := measure(text)
+				if line.indent+line.width+w > maxWidth && len(line.fragments) > 0 {
+					pushLine(false)
+				}
+
+				// Mot plus long qu'une ligne : coupé au caractère
+				for w > maxWidth-line.indent && utf8.RuneCountInString(text) > 1 {
+					head := b.fitPrefix(text, maxWidth-line.indent, measure)
+					add(head, measure(head), false)
+					pushLine(false)
+					text = text[len(head):]
+					w = measure(text)
+				}
+				add(text, w, false)
+			}
+		}
+	}
+
+	if len(line.fragments) > 0 || len(lines) == 0 {
+		pushLine(true)
+	} else if paragraph < len(lines) {
+		lines[len(lines)-1].end = true
+		b.setDirection(runs, lines[paragraph:])
+	}
+	return lines
+}
+
+// glyphCount retourne le nombre de caractères d'un texte traduit pour la police courante
+// (l'espacement des lettres s'applique à chacun)
+func (b *PDFBuilder) glyphCount(text string) int {
+	if b.utf8Fonts[strings.ToLower(b.font.family)] {
+		return utf8.RuneCountInString(text)
+	}
+	return len(text)
+}
+
+// fitPrefix retourne le plus long préfixe (au moins un caractère) tenant dans width selon measure
+func (b *PDFBuilder) fitPrefix(text string, width float64, measure func(string) float64) string {
+	var bounds []int
+	for i := range text {
+		if i > 0 {
+			bounds = append(bounds, i)
+		}
+	}
+	bounds = append(bounds, len(text))
+
+	end := bounds[0]
+	for _, bound := range bounds[1:] {
+		if measure(text[:bound]) > width {
+			break
+		}
+		end = bound
+	}
+	return text[:end]
+}
+
+// mergeFragments regroupe les morceaux consécutifs d'un même run ; les runs avec espacement
+// des mots restent découpés, chaque mot étant placé séparément
+func mergeFragments(runs []textRun, fragments []richFragment) []richFragment {
+	var merged []richFragment
+	for _, fragment := range fragments {
+		if n := len(merged); n > 0 && merged[n-1].run == fragment.run && runs[fragment.run].style.WordSpacing == 0 {
+			merged[n-1].text += fragment.text
+			merged[n-1].width += fragment.width
+			merged[n-1].space = false
+			continue
+		}
+		merged = append(merged, fragment)
+	}
+	return merged
+}
+
+// splitWords découpe un texte en mots, suites d'espaces et retours à la ligne
+func splitWords(text string) []string {
+	text = strings.ReplaceAll(text, "\r", "")
+
+	var tokens []string
+	start := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c != ' ' && c != '\n' {
+			continue
+		}
+		if i > start {
+			tokens = append(tokens, text[start:i])
+		}
+		if c == '\n' {
+			tokens = append(tokens, "\n")
+			start = i + 1
+			continue
+		}
+		// Suite d'espaces
+		j := i
+		for j < len(text) && text[j] == ' ' {
+			j++
+		}
+		tokens = append(tokens, text[i:j])
+		i = j - 1
+		start = j
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// richLines découpe le texte d'un élément en lignes pour la largeur donnée
+func (b *PDFBuilder) richLines(element Element, width float64) []richLine {
+	return b.wrapRuns(b.textRuns(element), width, firstLineIndent(element.Style))
+}
+
+// firstLineIndent retourne le retrait de première ligne des paragraphes d'un texte
+func firstLineIndent(style *Style) float64 {
+	if style == nil {
+		return 0
+	}
+	return style.FirstLineIndent
+}
+
+// drawRichLines écrit des lignes de texte riche à partir de (x, y), chacune de hauteur lineHeight,
+// alignées dans width ; les lignes de base d'une même ligne sont alignées sur la plus grande police.
+// En justification ("J"), l'espace restant est réparti entre les mots, sauf en fin de paragraphe.
+// Les lignes contenant du texte de droite à gauche sont réordonnées ; dans un paragraphe de droite
+// à gauche, le retrait est placé à droite et la dernière ligne justifiée est alignée à droite.
+func (b *PDFBuilder) drawRichLines(runs []textRun, lines []richLine, x, y, width, lineHeight float64, align string) {
+	// Write revient à la ligne à la marge droite : la lever le temps du dessin
+	b.pdf.SetRightMargin(0)
+	defer b.pdf.SetRightMargin(b.margins.right)
+
+	available := width - 2*b.pdf.GetCellMargin()
+	k := b.pdf.GetConversionRatio()
+
+	for _, line := range lines {
+		lineX := x + line.indent
+		base := 0
+		if line.rtl {
+			lineX, base = x, 1
+		}
+		extra := available - line.indent - line.width
+		switch {
+		case align == "C":
+			lineX += extra / 2
+		case align == "R", align == "J" && line.end && line.rtl:
+			lineX += extra
+		}
+
+		// Espace supplémentaire de chaque espace entre deux mots (justification)
+		stretch := 0.0
+		fragments := b.visualFragments(runs, line.fragments, base)
+		if align == "J" && !line.end && extra > 0 {
+			spaces := 0
+			for _, fragment := range fragments {
+				if fragment.space {
+					spaces++
+				}
+			}
+			if spaces > 0 {
+				stretch = extra / float64(spaces)
+			}
+		}
+		if stretch == 0 {
+			fragments = mergeFragments(runs, fragments)
+		}
+
+		for _, fragment := range fragments {
+			run := runs[fragment.run]
+			b.applyStyle(run.style)
+			// Police non chargée (variante absente) : Write paniquerait sur ses métriques vides
+			if b.pdf.Err() {
+				return
+			}
+
+			advance := fragment.width
+			if fragment.space {
+				advance += stretch
+			}
+
+			if run.style.BgColor != "" {
+				b.pdf.Rect(lineX+b.pdf.GetCellMargin(), y, advance, lineHeight, "F")
+			}
+
+			// L'espacement des lettres est un paramètre d'état du texte (Tc, en points)
+			if run.style.LetterSpacing != 0 {
+				b.pdf.RawWriteStr(fmt.Sprintf("%.3f Tc\n", run.style.LetterSpacing*k))
+			}
+
+			// CellFormat place la ligne de base à 0,3 × taille sous le milieu de la ligne
+			b.pdf.SetXY(lineX, y+0.3*(line.maxSize-fragment.size))
+			if run.link != "" {
+				b.pdf.WriteLinkString(lineHeight, fragment.text, run.link)
+			} else {
+				b.pdf.Write(lineHeight, fragment.text)
+			}
+
+			if run.style.LetterSpacing != 0 {
+				b.pdf.RawWriteStr("0 Tc\n")
+			}
+			lineX += advance
+		}
+
+		y += lineHeight
+	}
+
+	b.pdf.SetXY(b.area.x, y)
+}
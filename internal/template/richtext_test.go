@@ -0,0 +1,134 @@
+package template
+
+import (
+	"encoding/base64"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"un deux", []string{"un", " ", "deux"}},
+		{"  un  ", []string{"  ", "un", "  "}},
+		{"un\ndeux", []string{"un", "\n", "deux"}},
+		{"un \r\n\ndeux", []string{"un", " ", "\n", "\n", "deux"}},
+		{"", nil},
+	}
+	for _, test := range tests {
+		if got := splitWords(test.text); !slices.Equal(got, test.want) {
+			t.Errorf("%q : %q, attendu %q", test.text, got, test.want)
+		}
+	}
+}
+
+// wrapped décrit les lignes de wrapRuns : fragments séparés par « | », « ¶ » en fin de paragraphe
+func wrapped(lines []richLine) []string {
+	var out []string
+	for _, line := range lines {
+		var texts []string
+		for _, fragment := range line.fragments {
+			texts = append(texts, fragment.text)
+		}
+		text := strings.Join(texts, "|")
+		if line.end {
+			text += "¶"
+		}
+		out = append(out, text)
+	}
+	return out
+}
+
+func TestWrapRuns(t *testing.T) {
+	builder := NewPDFBuilder(Template{})
+	bold := &Style{Bold: true}
+	text := func(s string) []TextSpan { return []TextSpan{{Text: s}} }
+
+	tests := []struct {
+		name   string
+		spans  []TextSpan
+		width  float64
+		indent float64
+		want   []string
+	}{
+		{"une ligne", text("un deux trois"), 100, 0, []string{"un| |deux| |trois¶"}},
+		{"coupure aux espaces", text("un deux trois"), 20, 0, []string{"un| |deux", "trois¶"}},
+		{"retours à la ligne", text("un\ndeux"), 100, 0, []string{"un¶", "deux¶"}},
+		{"espaces de fin de ligne supprimés", text("un   "), 100, 0, []string{"un¶"}},
+		{"pas d'espace en début de ligne", text("  un"), 100, 0, []string{"un¶"}},
+		{"spans sur une ligne", []TextSpan{{Text: "Gras ", Style: bold}, {Text: "normal"}}, 100, 0, []string{"Gras| |normal¶"}},
+		{"spans coupés entre lignes", []TextSpan{{Text: "un deux ", Style: bold}, {Text: "trois quatre"}}, 20, 0, []string{"un| |deux", "trois| |quatre¶"}},
+		{"mot plus long qu'une ligne", text("anticonstitutionnellement"), 20, 0, []string{"anticonstitu", "tionnellem", "ent¶"}},
+		{"sans retrait", text("un deux trois"), 30, 0, []string{"un| |deux| |trois¶"}},
+		{"retrait de première ligne", text("un deux trois"), 30, 15, []string{"un| |deux", "trois¶"}},
+		{"retrait de chaque paragraphe", text("un deux trois\nquatre"), 30, 15, []string{"un| |deux", "trois¶", "quatre¶"}},
+	}
+	for _, test := range tests {
+		runs := builder.textRuns(Element{Type: "text", Spans: test.spans})
+		got := wrapped(builder.wrapRuns(runs, test.width, test.indent))
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s : %q, attendu %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestWrapRunsSpans(t *testing.T) {
+	// Chaque fragment garde le run (span) dont il provient, y compris après une coupure
+	builder := NewPDFBuilder(Template{})
+	runs := builder.textRuns(Element{Type: "text", Spans: []TextSpan{
+		{Text: "un deux ", Style: &Style{Bold: true}}, {Text: "trois quatre", Link: "https://example.com"},
+	}})
+	lines := builder.wrapRuns(runs, 20, 0)
+
+	var got []int
+	for _, line := range lines {
+		for _, fragment := range line.fragments {
+			got = append(got, fragment.run)
+		}
+	}
+	if want := []int{0, 0, 0, 1, 1, 1}; !slices.Equal(got, want) {
+		t.Errorf("runs des fragments %v, attendu %v", got, want)
+	}
+	if !runs[0].style.Bold || runs[1].style.Bold || runs[1].link == "" {
+		t.Error("style ou lien des spans perdu")
+	}
+
+	// Coupure au caractère : chaque ligne tient dans la largeur, cellule déduite
+	maxWidth := 20 - 2*builder.pdf.GetCellMargin()
+	for _, line := range builder.richLines(Element{Type: "text", Content: "anticonstitutionnellement"}, 20) {
+		if line.width > maxWidth+1e-9 {
+			t.Errorf("ligne %q de %.2f mm, plus large que %.2f mm", line.fragments[0].text, line.width, maxWidth)
+		}
+	}
+}
+
+func TestRichTextMissingVariant(t *testing.T) {
+	// Variante grasse non déclarée : erreur, pas de panique (texte réparti par wrapRuns ou non)
+	data, err := os.ReadFile("fontpack/DejaVuSans.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bold := &Style{Bold: true, LineHeight: 1.2}
+	tests := []struct {
+		name    string
+		element Element
+	}{
+		{"interligne", Element{Type: "text", Content: "T", Style: bold}},
+		{"texte simple", Element{Type: "text", Content: "T", Style: &Style{Bold: true}}},
+		{"span gras", Element{Type: "text", Spans: []TextSpan{{Text: "a "}, {Text: "T", Style: &Style{Bold: true}}}}},
+		{"cellule de grille", Element{Type: "grid", GridColumns: 2, Children: []Element{{Type: "text", Content: "T", Style: bold}}}},
+	}
+	for _, test := range tests {
+		template := Template{
+			Fonts:    FontConfig{Default: "Custom", Base64Data: map[string]string{"Custom": base64.StdEncoding.EncodeToString(data)}},
+			Elements: []Element{test.element},
+		}
+		if _, err := NewPDFBuilder(template).Build(); err == nil {
+			t.Errorf("%s : variante grasse absente sans erreur", test.name)
+		}
+	}
+}
@@ -23,30 +23,75 @@ type PageConfig struct {
 
 type FontConfig struct {
 	Default    string                      `json:"default"`
-	Paths      map[string]string           `json:"paths"`      // Chemins vers fichiers de polices (non-WASM)
-	Base64Data map[string]string           `json:"base64Data"` // Polices en base64 (pour WASM)
+	Paths      map[string]string           `json:"paths"`      // Chemins vers fichiers de polices TTF, OTF, WOFF ou WOFF2 (non-WASM)
+	Base64Data map[string]string           `json:"base64Data"` // Polices TTF, OTF, WOFF ou WOFF2 en base64 (pour WASM)
 	Embedded   map[string]EmbeddedFontData `json:"embedded"`   // Polices embarquées personnalisées
+	Fallbacks  map[string][]string         `json:"fallbacks"`  // Polices de secours par famille, dans l'ordre (caractères absents)
+
+	Definitions map[string]FontDefinition `json:"definitions"` // Polices 8 bits générées par makefont (page de codes autre que cp1252)
+}
+
+// FontDefinition est une police 8 bits générée par l'outil makefont de gofpdf
+type FontDefinition struct {
+	CodePage   string              `json:"codePage"`   // "cp1250", "cp1251", "iso-8859-15"... (défaut : celle de la définition)
+	Regular    FontDefinitionFiles `json:"regular"`    // Fichiers de la variante regular
+	Bold       FontDefinitionFiles `json:"bold"`       // Fichiers de la variante bold
+	Italic     FontDefinitionFiles `json:"italic"`     // Fichiers de la variante italic
+	BoldItalic FontDefinitionFiles `json:"boldItalic"` // Fichiers de la variante bold+italic
+}
+
+// FontDefinitionFiles contient les fichiers produits par makefont pour une variante
+type FontDefinitionFiles struct {
+	JSON string `json:"json"` // Base64 du fichier .json (métriques)
+	Z    string `json:"z"`    // Base64 du fichier .z (police compressée)
 }
 
 type EmbeddedFontData struct {
-	Regular    string `json:"regular"`    // Base64 du fichier TTF regular
-	Bold       string `json:"bold"`       // Base64 du fichier TTF bold
-	Italic     string `json:"italic"`     // Base64 du fichier TTF italic
-	BoldItalic string `json:"boldItalic"` // Base64 du fichier TTF bold+italic
+	Regular    string `json:"regular"`    // Base64 du fichier TTF, OTF, WOFF ou WOFF2 regular
+	Bold       string `json:"bold"`       // Base64 du fichier TTF, OTF, WOFF ou WOFF2 bold
+	Italic     string `json:"italic"`     // Base64 du fichier TTF, OTF, WOFF ou WOFF2 italic
+	BoldItalic string `json:"boldItalic"` // Base64 du fichier TTF, OTF, WOFF ou WOFF2 bold+italic
 }
 
 type Style struct {
-	Font    string  `json:"font,omitempty"`
-	Size    float64 `json:"size,omitempty"`
-	Bold    bool    `json:"bold,omitempty"`
-	Italic  bool    `json:"italic,omitempty"`
-	Color   string  `json:"color,omitempty"`   // hex color
-	BgColor string  `json:"bgColor,omitempty"` // hex background color
-	Align   string  `json:"align,omitempty"`   // "left", "center", "right"
-	Border  string  `json:"border,omitempty"`  // "0", "1", "LTR", etc.
-	Fill    bool    `json:"fill,omitempty"`    // remplir la cellule
-	Width   float64 `json:"width,omitempty"`   // largeur spécifique
-	Height  float64 `json:"height,omitempty"`  // hauteur spécifique
+	Font      string  `json:"font,omitempty"`
+	Size      float64 `json:"size,omitempty"`
+	Bold      bool    `json:"bold,omitempty"`
+	Italic    bool    `json:"italic,omitempty"`
+	Underline bool    `json:"underline,omitempty"`
+	Color     string  `json:"color,omitempty"`   // hex color
+	BgColor   string  `json:"bgColor,omitempty"` // hex background color
+	Align     string  `json:"align,omitempty"`   // "left", "center", "right", "justify"
+	Border    string  `json:"border,omitempty"`  // "0", "1", "LTR", etc.
+	Fill      bool    `json:"fill,omitempty"`    // remplir la cellule
+	Width     float64 `json:"width,omitempty"`   // largeur spécifique
+	Height    float64 `json:"height,omitempty"`  // hauteur spécifique
+	VAlign    string  `json:"valign,omitempty"`  // "top", "middle", "bottom" (enfants de grille)
+
+	// Typographie
+	LineHeight      float64 `json:"lineHeight,omitempty"`      // interligne en multiple de la taille de police (prioritaire sur height)
+	LetterSpacing   float64 `json:"letterSpacing,omitempty"`   // espace ajouté entre les caractères en mm
+	WordSpacing     float64 `json:"wordSpacing,omitempty"`     // espace ajouté entre les mots en mm
+	FirstLineIndent float64 `json:"firstLineIndent,omitempty"` // retrait de la première ligne de chaque paragraphe en mm
+	WhiteSpace      string  `json:"whiteSpace,omitempty"`      // "normal" (défaut : retour à la ligne automatique) ou "nowrap"
+	Overflow        string  `json:"overflow,omitempty"`        // texte sur une ligne qui dépasse : "ellipsis", "clip" ou "shrink"
+	Hyphenate       bool    `json:"hyphenate,omitempty"`       // césure des mots en fin de ligne (langue du document requise)
+
+	// Ajustement de la taille du texte
+	Fit *TextFit `json:"fit,omitempty"` // réduction de la taille de police pour tenir en maxLines lignes
+
+	// Sens d'écriture
+	Direction string `json:"direction,omitempty"` // "ltr" ou "rtl" (alignement à droite, colonnes de tableau inversées)
+
+	// Cadres (box)
+	BorderColor string  `json:"borderColor,omitempty"` // hex color des bordures
+	BorderWidth float64 `json:"borderWidth,omitempty"` // épaisseur des bordures en mm
+	Radius      float64 `json:"radius,omitempty"`      // rayon des coins arrondis en mm
+
+	// Pagination
+	BreakBefore  bool `json:"breakBefore,omitempty"`  // commencer l'élément sur une nouvelle page
+	KeepTogether bool `json:"keepTogether,omitempty"` // ne pas découper l'élément entre deux pages
+	KeepWithNext bool `json:"keepWithNext,omitempty"` // garder l'élément sur la même page que le début du suivant
 
 	// Espacement
 	Margin  []float64 `json:"margin,omitempty"`  // [top, right, bottom, left] ou [vertical, horizontal] ou [all]
@@ -54,36 +99,128 @@ type Style struct {
 }
 
 type Element struct {
-	Type     string      `json:"type"`               // "text", "table", "grid", "space", "line", "image"
+	Type     string      `json:"type"`               // "text", "table", "grid", "space", "line", "image", "box", "list", "markdown", "html", "pageBreak"
 	Content  interface{} `json:"content,omitempty"`  // contenu variable selon le type
+	Spans    []TextSpan  `json:"spans,omitempty"`    // texte riche : remplace content pour les textes
 	Style    *Style      `json:"style,omitempty"`    // style pour cet élément
-	Children []Element   `json:"children,omitempty"` // pour les grilles
+	Children []Element   `json:"children,omitempty"` // pour les grilles et les cadres
 
 	// Spécifique aux tableaux
 	Columns []TableColumn `json:"columns,omitempty"`
 	Rows    interface{}   `json:"rows,omitempty"` // Peut être []TableRow ou string (pour templates avec boucles)
 
+	// Tableaux liés aux données : les lignes sont générées depuis pdfVars
+	DataSource string `json:"dataSource,omitempty"` // chemin du tableau dans les variables (ex: "items")
+	SortBy     string `json:"sortBy,omitempty"`     // champ de tri
+	SortOrder  string `json:"sortOrder,omitempty"`  // "asc" (défaut) ou "desc"
+	EmptyText  string `json:"emptyText,omitempty"`  // texte affiché quand la source est vide
+
+	// Regroupement des lignes liées aux données
+	GroupBy     string           `json:"groupBy,omitempty"`     // champ de regroupement
+	GroupHeader *TableSummaryRow `json:"groupHeader,omitempty"` // ligne affichée avant chaque groupe
+	GroupFooter *TableSummaryRow `json:"groupFooter,omitempty"` // sous-total affiché après chaque groupe
+	GrandTotal  *TableSummaryRow `json:"grandTotal,omitempty"`  // total affiché en fin de tableau
+
+	// Lignes de report des totaux à chaque saut de page
+	CarryForward *TableCarryForward `json:"carryForward,omitempty"`
+
 	// Spécifique aux grilles
-	GridColumns int `json:"gridColumns,omitempty"`
+	GridColumns  int           `json:"gridColumns,omitempty"`
+	ColumnWidths []interface{} `json:"columnWidths,omitempty"` // largeur de chaque colonne : mm (40), pourcentage ("30%") ou fraction ("1fr")
+	Gap          *float64      `json:"gap,omitempty"`          // espace horizontal entre colonnes (défaut 2)
+	RowGap       *float64      `json:"rowGap,omitempty"`       // espace vertical entre lignes (défaut = gap)
+	ColSpan      int           `json:"colSpan,omitempty"`      // nombre de colonnes occupées par un enfant de grille
 
 	// Spécifique aux lignes
 	Length float64 `json:"length,omitempty"`
+
+	// Spécifique aux listes
+	Items     []interface{} `json:"items,omitempty"`     // chaînes ou tableaux d'éléments (contenu imbriqué)
+	Ordered   bool          `json:"ordered,omitempty"`   // liste numérotée
+	Bullet    string        `json:"bullet,omitempty"`    // puce personnalisée (listes non numérotées)
+	Numbering string        `json:"numbering,omitempty"` // format de numérotation : "1.", "a)", "i.)", "A.", "I."
+	Start     int           `json:"start,omitempty"`     // premier numéro (défaut 1)
+	Indent    float64       `json:"indent,omitempty"`    // retrait du contenu des éléments en mm (défaut 6)
+	listLevel int           // profondeur d'imbrication, pour les puces par défaut
+
+	// Positionnement absolu : l'élément est dessiné aux coordonnées données, hors du flux
+	Position *Position `json:"position,omitempty"`
+}
+
+// TextSpan est un morceau de texte riche avec son propre style
+type TextSpan struct {
+	Text  string `json:"text"`
+	Style *Style `json:"style,omitempty"` // style partiel, fusionné avec celui de l'élément
+	Link  string `json:"link,omitempty"`  // URL ouverte au clic
+}
+
+// Position place un élément à des coordonnées absolues (mm)
+type Position struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Anchor string  `json:"anchor,omitempty"` // "page" (défaut, alias "top-left") : coin de la page ; "margin" : coin de la zone de contenu
 }
 
 type TableColumn struct {
 	Header string  `json:"header"`
 	Width  float64 `json:"width"`
 	Align  string  `json:"align,omitempty"`
+
+	// Liaison aux données (tableaux avec dataSource)
+	Field  string `json:"field,omitempty"`  // chemin du champ dans chaque item (ex: "unitPrice", "product.name")
+	Format string `json:"format,omitempty"` // format printf appliqué à la valeur (ex: "%.2f €")
 }
 
 type TableRow struct {
 	Cells []string `json:"cells"`
 	Style *Style   `json:"style,omitempty"`
+
+	kind   rowKind       // type de ligne généré par les tableaux liés aux données
+	values []interface{} // valeurs brutes des colonnes liées aux données (avant format)
+}
+
+// value retourne la valeur brute d'une colonne, ou le texte de la cellule pour les lignes statiques
+func (r TableRow) value(column int) interface{} {
+	if column < len(r.values) && r.values[column] != nil {
+		return r.values[column]
+	}
+	if column < len(r.Cells) {
+		return r.Cells[column]
+	}
+	return nil
+}
+
+type rowKind int
+
+const (
+	rowData         rowKind = iota
+	rowEmpty                // texte fusionné affiché quand la source est vide
+	rowGroupHeader          // en-tête de groupe fusionné sur toute la largeur
+	rowGroupFooter          // sous-total d'un groupe
+	rowGrandTotal           // total général
+	rowCarryForward         // report du cumul en bas / haut de page
+)
+
+// TableCarryForward ajoute une ligne « À reporter » en bas de page et « Report » en haut de la
+// page suivante, avec le cumul d'une colonne numérique
+type TableCarryForward struct {
+	Field       string `json:"field"`                 // champ lié (ou en-tête) de la colonne cumulée
+	BottomLabel string `json:"bottomLabel,omitempty"` // libellé en bas de page (défaut "À reporter")
+	TopLabel    string `json:"topLabel,omitempty"`    // libellé en haut de page suivante (défaut "Report")
+	Style       *Style `json:"style,omitempty"`
+}
+
+// TableSummaryRow décrit une ligne d'en-tête de groupe, de sous-total ou de total général
+type TableSummaryRow struct {
+	Label      string            `json:"label,omitempty"`      // format printf : %s reçoit la valeur du groupe
+	Aggregates map[string]string `json:"aggregates,omitempty"` // champ -> "sum", "avg", "min", "max" ou "count"
+	Style      *Style            `json:"style,omitempty"`
 }
 
 type Template struct {
 	Page     PageConfig `json:"page"`
 	Fonts    FontConfig `json:"fonts"`
+	Language string     `json:"language,omitempty"` // langue du document ("fr", "en-US", "de"...), utilisée pour la césure
 	Elements []Element  `json:"elements"`
 }
 
@@ -93,7 +230,23 @@ type PDFBuilder struct {
 	pdf     *gofpdf.Fpdf
 	config  Template
 	margins struct{ left, top, right, bottom float64 }
-	tr      func(string) string // Unicode translator
+	area    struct{ x, width, height float64 } // Zone de mise en page courante (page entière ou colonne de grille), height > 0 dans un cadre de hauteur imposée
+	font    fontState                          // Police courante (voir setFont)
+	vars    map[string]interface{}             // Variables (pdfVars) pour les tableaux liés aux données
+
+	utf8Fonts   map[string]bool          // Familles chargées en UTF-8 (TTF), en minuscules
+	codePages   map[string]string        // Page de codes des polices 8 bits (fonts.definitions), en minuscules
+	fontSources map[string]declaredFont  // Variantes UTF-8 déclarées (famille en minuscules + style), voir loadFont
+	fontsAdded  map[string]bool          // Variantes UTF-8 déjà ajoutées au document (famille + style)
+	coverage    map[string]glyphCoverage // Caractères contenus par les familles UTF-8, en minuscules
+	fontFiles   map[string]fontFile      // Fichiers de police ajoutés, par nom de police dans le PDF (voir FontReport)
+	fontsLoaded bool                     // setupFonts déjà exécuté
+	totalPages  int                      // nombre de pages calculé par Layout
+}
+
+type fontState struct {
+	family, style string
+	size          float64
 }
 
 func NewPDFBuilder(template Template) *PDFBuilder {
@@ -120,8 +273,14 @@ func NewPDFBuilder(template Template) *PDFBuilder {
 	pdf := gofpdf.New(orientation, "mm", template.Page.Format, "")
 
 	builder := &PDFBuilder{
-		pdf:    pdf,
-		config: template,
+		pdf:         pdf,
+		config:      template,
+		utf8Fonts:   make(map[string]bool),
+		coverage:    make(map[string]glyphCoverage),
+		codePages:   make(map[string]string),
+		fontSources: make(map[string]declaredFont),
+		fontsAdded:  make(map[string]bool),
+		fontFiles:   make(map[string]fontFile),
 	}
 
 	// Marges
@@ -133,58 +292,47 @@ func NewPDFBuilder(template Template) *PDFBuilder {
 	pdf.SetMargins(builder.margins.left, builder.margins.top, builder.margins.right)
 	pdf.SetAutoPageBreak(true, builder.margins.bottom)
 
-	// Initialiser le traducteur Unicode pour supporter l'UTF-8 avec les polices standard
-	builder.tr = pdf.UnicodeTranslatorFromDescriptor("")
+	// Zone de mise en page initiale : toute la largeur entre les marges
+	pageWidth, _ := pdf.GetPageSize()
+	builder.area.x = builder.margins.left
+	builder.area.width = pageWidth - builder.margins.left - builder.margins.right
 
 	return builder
 }
 
 func (b *PDFBuilder) setupFonts() {
-	// Vérifier si on utilise des polices système standard
-	isStandardFont := func(fontName string) bool {
-		standardFonts := []string{"Arial", "Helvetica", "Times", "Courier"}
-		for _, std := range standardFonts {
-			if strings.EqualFold(fontName, std) {
-				return true
-			}
-		}
-		return false
-	}
-
-	// Si on utilise une police système standard, pas besoin de setup spécial
-	if isStandardFont(b.config.Fonts.Default) {
-		// Les polices standard sont déjà disponibles dans gofpdf
+	if b.fontsLoaded {
 		return
 	}
+	b.fontsLoaded = true
+
+	// Les polices standard (Arial, Helvetica, Times, Courier) sont déjà disponibles dans gofpdf ;
+	// les polices déclarées sont toujours disponibles pour style.font, et ajoutées au document
+	// à leur première utilisation (voir loadFont). Les fichiers décodés viennent du registre
+	// partagé par les documents du processus (voir fontregistry.go).
 
 	// 1. Ajouter les polices embarquées (structure complète avec variants)
 	for fontName, fontData := range b.config.Fonts.Embedded {
-		if fontData.Regular != "" {
-			if data, err := base64.StdEncoding.DecodeString(fontData.Regular); err == nil {
-				b.pdf.AddUTF8FontFromBytes(fontName, "", data)
-			}
-		}
-		if fontData.Bold != "" {
-			if data, err := base64.StdEncoding.DecodeString(fontData.Bold); err == nil {
-				b.pdf.AddUTF8FontFromBytes(fontName, "B", data)
-			}
+		variants := []struct{ style, data string }{
+			{"", fontData.Regular},
+			{"B", fontData.Bold},
+			{"I", fontData.Italic},
+			{"BI", fontData.BoldItalic},
 		}
-		if fontData.Italic != "" {
-			if data, err := base64.StdEncoding.DecodeString(fontData.Italic); err == nil {
-				b.pdf.AddUTF8FontFromBytes(fontName, "I", data)
+		for _, variant := range variants {
+			if variant.data == "" {
+				continue
 			}
-		}
-		if fontData.BoldItalic != "" {
-			if data, err := base64.StdEncoding.DecodeString(fontData.BoldItalic); err == nil {
-				b.pdf.AddUTF8FontFromBytes(fontName, "BI", data)
+			if font, err := registerBase64Font(variant.data); err == nil {
+				b.declareFont(fontName, variant.style, font)
 			}
 		}
 	}
 
 	// 2. Ajouter les polices base64 simples (pour compatibilité)
 	for fontName, base64Data := range b.config.Fonts.Base64Data {
-		if data, err := base64.StdEncoding.DecodeString(base64Data); err == nil {
-			b.pdf.AddUTF8FontFromBytes(fontName, "", data)
+		if font, err := registerBase64Font(base64Data); err == nil {
+			b.declareFont(fontName, "", font)
 		}
 		// Debug seulement en développement
 		// else { fmt.Printf("Warning: Failed to decode base64 font %s: %v\n", fontName, err) }
@@ -193,22 +341,61 @@ func (b *PDFBuilder) setupFonts() {
 	// 3. Ajouter les polices depuis fichiers (non-WASM uniquement)
 	for fontName, path := range b.config.Fonts.Paths {
 		// Essayer de charger depuis le fichier pour les polices personnalisées
-		if _, err := os.Stat(path); err == nil {
-			b.pdf.AddUTF8Font(fontName, "", path)
+		if font, err := registerFontFile(path); err == nil {
+			b.declareFont(fontName, "", font)
 		}
 		// Debug seulement en développement
 		// else { fmt.Printf("Warning: Custom font %s not found at %s (normal en mode WASM)\n", fontName, path) }
 	}
+
+	// 4. Ajouter les polices 8 bits générées par makefont, avec leur page de codes
+	for fontName, definition := range b.config.Fonts.Definitions {
+		if err := b.addFontDefinition(fontName, definition); err != nil {
+			b.pdf.SetError(err)
+		}
+	}
+
+	// 5. Familles du pack intégré non déclarées (build tag fontpack), chargées à la demande
+	b.setupFontPack()
+}
+
+// setFont sélectionne une police en mémorisant la sélection, pour pouvoir la restaurer après une mesure
+func (b *PDFBuilder) setFont(family, style string, size float64) {
+	b.font = fontState{family: family, style: style, size: size}
+	b.loadFont(family, style)
+	b.pdf.SetFont(family, style, size)
 }
 
 func (b *PDFBuilder) applyStyle(style *Style) {
+	b.applyFont(style)
+
 	if style == nil {
-		b.pdf.SetFont(b.config.Fonts.Default, "", 10)
 		b.pdf.SetTextColor(0, 0, 0)
 		return
 	}
 
-	// Police
+	// Couleur du texte
+	if style.Color != "" {
+		r, g, blue := hexToRGB(style.Color)
+		b.pdf.SetTextColor(r, g, blue)
+	} else {
+		b.pdf.SetTextColor(0, 0, 0)
+	}
+
+	// Couleur de fond
+	if style.BgColor != "" {
+		r, g, blue := hexToRGB(style.BgColor)
+		b.pdf.SetFillColor(r, g, blue)
+	}
+}
+
+// applyFont sélectionne uniquement la police d'un style (utilisé aussi pour les mesures)
+func (b *PDFBuilder) applyFont(style *Style) {
+	if style == nil {
+		b.setFont(b.config.Fonts.Default, "", 10)
+		return
+	}
+
 	font := b.config.Fonts.Default
 	if style.Font != "" {
 		font = style.Font
@@ -221,30 +408,25 @@ func (b *PDFBuilder) applyStyle(style *Style) {
 	if style.Italic {
 		fontStyle += "I"
 	}
+	if style.Underline {
+		fontStyle += "U"
+	}
 
 	size := 10.0
 	if style.Size > 0 {
 		size = style.Size
 	}
 
-	b.pdf.SetFont(font, fontStyle, size)
-
-	// Couleur du texte
-	if style.Color != "" {
-		r, g, blue := hexToRGB(style.Color)
-		b.pdf.SetTextColor(r, g, blue)
-	} else {
-		b.pdf.SetTextColor(0, 0, 0)
-	}
-
-	// Couleur de fond
-	if style.BgColor != "" {
-		r, g, blue := hexToRGB(style.BgColor)
-		b.pdf.SetFillColor(r, g, blue)
-	}
+	b.setFont(font, fontStyle, size)
 }
 
 func (b *PDFBuilder) renderElement(element Element) {
+	// Les éléments positionnés sont dessinés hors du flux
+	if element.Position != nil {
+		b.renderPositioned(element)
+		return
+	}
+
 	// Appliquer les marges universelles pour tous les éléments
 	b.applyMargin(element.Style)
 
@@ -261,6 +443,12 @@ func (b *PDFBuilder) renderElement(element Element) {
 		b.renderLine(element)
 	case "image":
 		b.renderImage(element)
+	case "box":
+		b.renderBox(element)
+	case "list":
+		b.renderList(element)
+	case "markdown", "html":
+		b.renderBlocks(element)
 	}
 
 	// Appliquer la marge du bas pour tous les éléments
@@ -273,6 +461,16 @@ func (b *PDFBuilder) renderElement(element Element) {
 }
 
 func (b *PDFBuilder) renderText(element Element) {
+	b.renderTextLines(element, 0, 0)
+}
+
+// renderTextLines dessine un texte ; pour un fragment de texte découpé entre plusieurs pages,
+// seules les lignes [from, to) sont dessinées (to == 0 : texte entier)
+func (b *PDFBuilder) renderTextLines(element Element, from, to int) {
+	// Calculer la largeur effective avec marges et padding
+	contentWidth, leftOffset := b.getContentArea(b.area.width, element.Style)
+	element = b.fitText(element, contentWidth)
+
 	b.applyStyle(element.Style)
 
 	content := ""
@@ -280,92 +478,271 @@ func (b *PDFBuilder) renderText(element Element) {
 		content = str
 	}
 
-	align := "L"
-	if element.Style != nil && element.Style.Align != "" {
-		switch element.Style.Align {
-		case "center":
-			align = "C"
-		case "right":
-			align = "R"
-		}
-	}
+	align := textAlign(element.Style)
 
-	height := 8.0
-	if element.Style != nil && element.Style.Height > 0 {
-		height = element.Style.Height
-	}
+	height := textLineHeight(element.Style, 8)
 
 	fill := false
 	if element.Style != nil && element.Style.Fill {
 		fill = true
 	}
 
-	// Calculer la largeur effective avec marges et padding
-	pageWidth, _ := b.pdf.GetPageSize()
-	availableWidth := pageWidth - b.margins.left - b.margins.right
-	contentWidth, leftOffset := b.getContentArea(availableWidth, element.Style)
+	// Texte riche ou typographie : lignes calculées par wrapRuns, seules les lignes [from, to) sont dessinées
+	if b.richLayout(element) {
+		runs := b.textRuns(element)
+		lines := b.wrapRuns(runs, contentWidth, firstLineIndent(element.Style))
+		if to > 0 {
+			if to > len(lines) {
+				to = len(lines)
+			}
+			lines = lines[from:to]
+		}
+		b.drawRichLines(runs, lines, b.area.x+leftOffset, b.pdf.GetY(), contentWidth, height, align)
+		return
+	}
+
+	// Se placer au début de la zone courante
+	b.pdf.SetX(b.area.x + leftOffset)
 
-	// Ajuster la position si nécessaire
-	if leftOffset > 0 {
-		currentX := b.pdf.GetX()
-		b.pdf.SetX(currentX + leftOffset)
+	if noWrap(element.Style) {
+		b.renderSingleLine(element.Style, content, contentWidth, height, "", align, fill)
+		return
 	}
 
-	// Si le contenu contient des retours à la ligne, utiliser MultiCell
-	if strings.Contains(content, "\n") {
+	// Si le contenu contient des retours à la ligne ou dépasse la largeur, utiliser MultiCell
+	text := b.encode(content)
+	lines := b.wrapLines(text, contentWidth)
+	if strings.Contains(content, "\n") || len(lines) > 1 {
 		border := ""
 		if element.Style != nil && element.Style.Border != "" {
 			border = element.Style.Border
 		}
-		b.pdf.MultiCell(contentWidth, height, b.tr(content), border, align, fill)
+
+		if to > 0 {
+			if to > len(lines) {
+				to = len(lines)
+			}
+			text = strings.Join(lines[from:to], "\n")
+		}
+		b.pdf.MultiCell(contentWidth, height, text, border, align, fill)
 	} else {
-		b.pdf.CellFormat(contentWidth, height, b.tr(content), "", 1, align, fill, 0, "")
+		b.pdf.CellFormat(contentWidth, height, text, "", 1, align, fill, 0, "")
+	}
+}
+
+// tableRowHeight est la hauteur des lignes de tableau (en-têtes compris)
+const tableRowHeight = 8.0
+
+// fitColumns retourne les colonnes d'un tableau dans une zone de largeur donnée : réduites
+// proportionnellement si leur somme dépasse la largeur, inchangées sinon
+func fitColumns(columns []TableColumn, width float64) []TableColumn {
+	total := 0.0
+	for _, col := range columns {
+		total += col.Width
+	}
+	if total <= width || width <= 0 {
+		return columns
+	}
+
+	fitted := make([]TableColumn, len(columns))
+	for c, col := range columns {
+		fitted[c] = col
+		fitted[c].Width = col.Width * width / total
 	}
+	return fitted
 }
 
 func (b *PDFBuilder) renderTable(element Element) {
-	if len(element.Columns) == 0 {
+	rows := b.tableRows(element)
+	b.renderTableRows(element, rows, 0, len(rows))
+}
+
+// renderTableRows dessine les en-têtes puis les lignes [from, to) d'un tableau à la position courante.
+// Pour un fragment de tableau découpé entre plusieurs pages, les lignes de report sont ajoutées
+// en haut (des lignes précèdent le fragment) et en bas (des lignes le suivent) ; les en-têtes
+// ne sont répétés que pour un tableau lié aux données.
+func (b *PDFBuilder) renderTableRows(element Element, rows []TableRow, from, to int) {
+	if len(element.Columns) == 0 || len(rows) == 0 {
 		return
 	}
 
-	// Calculer l'alignement du tableau
+	// Calculer l'alignement du tableau (à droite par défaut de droite à gauche)
 	tableAlign := "L"
-	if element.Style != nil && element.Style.Align != "" {
-		switch element.Style.Align {
-		case "center":
-			tableAlign = "C"
-		case "right":
-			tableAlign = "R"
-		}
+	if element.Style != nil {
+		tableAlign = directionAlign(element.Style.Align, element.Style.Direction)
 	}
 
-	// Calculer la largeur totale du tableau
+	// Calculer la largeur totale du tableau, colonnes réduites à la zone courante
+	element.Columns = fitColumns(element.Columns, b.area.width)
 	totalWidth := 0.0
 	for _, col := range element.Columns {
 		totalWidth += col.Width
 	}
 
-	// Calculer la position X selon l'alignement
-	pageWidth, _ := b.pdf.GetPageSize()
-	availableWidth := pageWidth - b.margins.left - b.margins.right
-
+	// Calculer la position X selon l'alignement dans la zone courante
 	var startX float64
 	switch tableAlign {
 	case "C":
-		startX = b.margins.left + (availableWidth-totalWidth)/2
+		startX = b.area.x + (b.area.width-totalWidth)/2
 	case "R":
-		startX = b.margins.left + (availableWidth - totalWidth)
+		startX = b.area.x + (b.area.width - totalWidth)
 	default:
-		startX = b.margins.left
+		startX = b.area.x
 	}
 
 	// Sauvegarder la position actuelle et se placer pour le tableau
 	currentY := b.pdf.GetY()
 	b.pdf.SetXY(startX, currentY)
 
-	// Gérer les différents types de Rows
+	rowHeight := tableRowHeight
+
+	// Tableau de droite à gauche : la première colonne est dessinée à droite
+	direction := ""
+	if element.Style != nil {
+		direction = element.Style.Direction
+	}
+	order := make([]int, len(element.Columns))
+	for c := range order {
+		order[c] = c
+		if direction == "rtl" {
+			order[c] = len(order) - 1 - c
+		}
+	}
+
+	// En-têtes
+	drawHeader := func() {
+		b.applyStyle(element.Style)
+
+		for _, c := range order {
+			col := element.Columns[c]
+			fill := element.Style != nil && element.Style.Fill
+			border := "1"
+			if element.Style != nil && element.Style.Border != "" {
+				border = element.Style.Border
+			}
+
+			align := directionAlign(col.Align, direction)
+			if element.Style != nil && element.Style.Fit != nil {
+				b.drawFitCell(element.Style.Fit, col.Width, rowHeight, col.Header, direction, border, align, fill)
+				continue
+			}
+			b.drawTextCell(col.Width, rowHeight, col.Header, direction, border, 0, align, fill)
+		}
+
+		// Nouvelle ligne en gardant la position X
+		currentY = b.pdf.GetY()
+		b.pdf.SetXY(startX, currentY+rowHeight)
+	}
+
+	drawRow := func(row TableRow) {
+		if row.Style != nil {
+			b.applyStyle(row.Style)
+		} else {
+			b.setFont(b.config.Fonts.Default, "", 10)
+			b.pdf.SetTextColor(0, 0, 0)
+		}
+		// Les lignes statiques ne sont pas remplies (rendu d'origine), même avec bgColor
+		fill := isDataTable(element) && row.Style != nil && row.Style.BgColor != ""
+
+		// Ajustement des cellules : style de la ligne, sinon celui du tableau
+		var fit *TextFit
+		if row.Style != nil && row.Style.Fit != nil {
+			fit = row.Style.Fit
+		} else if element.Style != nil {
+			fit = element.Style.Fit
+		}
+
+		// Ligne fusionnée sur toute la largeur (tableau vide, en-tête de groupe)
+		if row.kind == rowEmpty || row.kind == rowGroupHeader {
+			text := ""
+			if len(row.Cells) > 0 {
+				text = row.Cells[0]
+			}
+			align := "C"
+			if row.kind == rowGroupHeader {
+				align = directionAlign("", direction)
+			}
+			b.drawTextCell(totalWidth, rowHeight, text, direction, "1", 0, align, fill)
+		} else {
+			for _, c := range order {
+				col := element.Columns[c]
+				if c >= len(row.Cells) {
+					// Cellule absente : les suivantes restent sous leur colonne
+					b.pdf.SetX(b.pdf.GetX() + col.Width)
+					continue
+				}
+				cell := row.Cells[c]
+
+				align := directionAlign(col.Align, direction)
+				if fit != nil {
+					b.drawFitCell(fit, col.Width, rowHeight, cell, direction, "1", align, fill)
+					continue
+				}
+				b.drawTextCell(col.Width, rowHeight, cell, direction, "1", 0, align, fill)
+			}
+		}
+
+		// Nouvelle ligne en gardant la position X
+		currentY = b.pdf.GetY()
+		b.pdf.SetXY(startX, currentY+rowHeight)
+	}
+
+	if from == 0 || isDataTable(element) {
+		drawHeader()
+	}
+	for _, row := range fragmentRows(element, rows, from, to) {
+		drawRow(row)
+	}
+}
+
+// fragmentRows retourne les lignes dessinées pour le fragment [from, to) d'un tableau : les
+// lignes de données, précédées de « Report » et suivies de « À reporter » (cumul de la colonne
+// suivie par carryForward) quand le tableau continue d'une page à l'autre
+func fragmentRows(element Element, rows []TableRow, from, to int) []TableRow {
+	carry := element.CarryForward
+	carryColumn := -1
+	if carry != nil {
+		carryColumn = carryForwardColumn(element.Columns, carry.Field)
+	}
+
+	var out []TableRow
+	if carryColumn >= 0 && from > 0 {
+		if total, ok := runningTotal(rows[:from], carryColumn); ok {
+			out = append(out, carryForwardRow(carry, element.Columns, carryColumn, total, carry.TopLabel, "Report"))
+		}
+	}
+	out = append(out, rows[from:to]...)
+	if carryColumn >= 0 && to < len(rows) {
+		if total, ok := runningTotal(rows[:to], carryColumn); ok {
+			out = append(out, carryForwardRow(carry, element.Columns, carryColumn, total, carry.BottomLabel, "À reporter"))
+		}
+	}
+	return out
+}
+
+// tableRows retourne les lignes d'un tableau : liées à une source de données ou déclarées dans le template
+func (b *PDFBuilder) tableRows(element Element) []TableRow {
+	if isDataTable(element) {
+		return b.dataRows(element)
+	}
+	return parseTableRows(element.Rows)
+}
+
+// cellAlign convertit l'alignement d'une colonne en alignement gofpdf
+func cellAlign(align string) string {
+	switch align {
+	case "center":
+		return "C"
+	case "right":
+		return "R"
+	}
+	return "L"
+}
+
+// parseTableRows convertit les lignes déclarées dans le template ([]TableRow ou JSON générique)
+func parseTableRows(raw interface{}) []TableRow {
 	var rows []TableRow
-	switch r := element.Rows.(type) {
+	switch r := raw.(type) {
 	case []TableRow:
 		rows = r
 	case []interface{}:
@@ -418,134 +795,46 @@ func (b *PDFBuilder) renderTable(element Element) {
 		}
 	case string:
 		// Template string avec boucles - ne rien faire ici car c'est géré par le template processor
-		return
+		return nil
 	default:
-		return
-	}
-
-	if len(rows) == 0 {
-		return
-	}
-
-	// En-têtes
-	if element.Style != nil {
-		b.applyStyle(element.Style)
-	}
-
-	for _, col := range element.Columns {
-		align := "L"
-		if col.Align == "center" {
-			align = "C"
-		} else if col.Align == "right" {
-			align = "R"
-		}
-
-		fill := element.Style != nil && element.Style.Fill
-		border := "1"
-		if element.Style != nil && element.Style.Border != "" {
-			border = element.Style.Border
-		}
-
-		b.pdf.CellFormat(col.Width, 8, b.tr(col.Header), border, 0, align, fill, 0, "")
+		return nil
 	}
 
-	// Nouvelle ligne en gardant la position X
-	currentY = b.pdf.GetY()
-	b.pdf.SetXY(startX, currentY+8) // Lignes de données
-	for _, row := range rows {
-		if row.Style != nil {
-			b.applyStyle(row.Style)
-		} else {
-			b.pdf.SetFont(b.config.Fonts.Default, "", 10)
-			b.pdf.SetTextColor(0, 0, 0)
-		}
-
-		for i, cell := range row.Cells {
-			if i >= len(element.Columns) {
-				break
-			}
-
-			col := element.Columns[i]
-			align := "L"
-			if col.Align == "center" {
-				align = "C"
-			} else if col.Align == "right" {
-				align = "R"
-			}
-
-			b.pdf.CellFormat(col.Width, 8, b.tr(cell), "1", 0, align, false, 0, "")
-		}
-
-		// Nouvelle ligne en gardant la position X
-		currentY = b.pdf.GetY()
-		b.pdf.SetXY(startX, currentY+8)
-	}
+	return rows
 }
 
-func (b *PDFBuilder) renderGrid(element Element) {
-	if element.GridColumns <= 0 || len(element.Children) == 0 {
-		return
-	}
-
-	// Calculer la largeur de chaque colonne
-	pageWidth, _ := b.pdf.GetPageSize()
-	availableWidth := pageWidth - b.margins.left - b.margins.right
-	columnWidth := availableWidth / float64(element.GridColumns)
-
-	// Traiter les éléments par ligne complète
-	for i := 0; i < len(element.Children); i += element.GridColumns {
-		startY := b.pdf.GetY()
-		maxHeight := 0.0
-
-		// Premier passage : calculer la hauteur maximale de la ligne
-		for col := 0; col < element.GridColumns && i+col < len(element.Children); col++ {
-			x := b.margins.left + float64(col)*columnWidth
-
-			// Simuler le rendu pour calculer la hauteur
-			b.pdf.SetXY(x, startY)
-			beforeY := b.pdf.GetY()
-
-			// Sauvegarder l'état avant le rendu temporaire
-			savedX, savedY := b.pdf.GetXY()
-
-			// Rendre l'élément temporairement pour mesurer
-			b.renderElementInWidth(element.Children[i+col], columnWidth-2) // -2 pour marge
-
-			afterY := b.pdf.GetY()
-			height := afterY - beforeY
-			if height > maxHeight {
-				maxHeight = height
-			}
-
-			// Restaurer la position
-			b.pdf.SetXY(savedX, savedY)
-		}
-
-		// Deuxième passage : rendre tous les éléments de la ligne avec la même hauteur
-		for col := 0; col < element.GridColumns && i+col < len(element.Children); col++ {
-			x := b.margins.left + float64(col)*columnWidth
-			b.pdf.SetXY(x, startY)
-
-			// Rendre l'élément dans sa colonne
-			b.renderElementInWidth(element.Children[i+col], columnWidth-2)
+// renderElementInWidth rend un élément dans une colonne (x, largeur) : la zone de mise en page
+// est restreinte le temps du rendu, si bien que tous les types d'éléments (y compris les grilles) s'y adaptent
+func (b *PDFBuilder) renderElementInWidth(element Element, x, width float64) {
+	b.withArea(x, width, func() {
+		switch {
+		case element.Position != nil:
+			b.renderPositioned(element)
+		case element.Type == "text":
+			b.renderTextInWidth(element)
+		default:
+			b.renderElement(element)
 		}
+	})
+}
 
-		// Avancer à la ligne suivante
-		b.pdf.SetXY(b.margins.left, startY+maxHeight+2)
-	}
+// withArea exécute fn avec une zone de mise en page restreinte, puis restaure la zone précédente
+func (b *PDFBuilder) withArea(x, width float64, fn func()) {
+	saved := b.area
+	b.area.x = x
+	b.area.width = width
+	defer func() { b.area = saved }()
+	fn()
 }
 
-// Nouvelle fonction pour rendre un élément dans une largeur spécifique
-func (b *PDFBuilder) renderElementInWidth(element Element, maxWidth float64) {
-	switch element.Type {
-	case "text":
-		b.renderTextInWidth(element, maxWidth)
-	default:
-		b.renderElement(element) // Fallback pour les autres types
-	}
+func (b *PDFBuilder) renderTextInWidth(element Element) {
+	b.renderTextInWidthLines(element, 0, 0)
 }
 
-func (b *PDFBuilder) renderTextInWidth(element Element, maxWidth float64) {
+// renderTextInWidthLines dessine le texte d'une cellule de grille ; pour un fragment découpé
+// entre plusieurs pages, seules les lignes [from, to) sont dessinées (to == 0 : texte entier)
+func (b *PDFBuilder) renderTextInWidthLines(element Element, from, to int) {
+	element = b.fitText(element, b.area.width)
 	b.applyStyle(element.Style)
 
 	content := ""
@@ -553,20 +842,9 @@ func (b *PDFBuilder) renderTextInWidth(element Element, maxWidth float64) {
 		content = str
 	}
 
-	align := "L"
-	if element.Style != nil && element.Style.Align != "" {
-		switch element.Style.Align {
-		case "center":
-			align = "C"
-		case "right":
-			align = "R"
-		}
-	}
+	align := textAlign(element.Style)
 
-	height := 5.0
-	if element.Style != nil && element.Style.Height > 0 {
-		height = element.Style.Height
-	}
+	height := textLineHeight(element.Style, 5)
 
 	fill := false
 	if element.Style != nil && element.Style.Fill {
@@ -578,11 +856,37 @@ func (b *PDFBuilder) renderTextInWidth(element Element, maxWidth float64) {
 		border = element.Style.Border
 	}
 
-	// Si le contenu contient des retours à la ligne, utiliser MultiCell
-	if strings.Contains(content, "\n") {
-		b.pdf.MultiCell(maxWidth, height, b.tr(content), border, align, fill)
+	// Texte riche : une seule ligne occupe 1,5 fois la hauteur, comme un texte simple
+	// (sauf interligne explicite)
+	if b.richLayout(element) {
+		runs := b.textRuns(element)
+		lines := b.wrapRuns(runs, b.area.width, firstLineIndent(element.Style))
+		if len(lines) == 1 && (element.Style == nil || element.Style.LineHeight == 0) {
+			height *= 1.5
+		}
+		if to > 0 {
+			lines = lines[from:min(to, len(lines))]
+		}
+		b.drawRichLines(runs, lines, b.area.x, b.pdf.GetY(), b.area.width, height, align)
+		return
+	}
+
+	b.pdf.SetX(b.area.x)
+
+	if noWrap(element.Style) {
+		b.renderSingleLine(element.Style, content, b.area.width, height*1.5, border, align, fill)
+		return
+	}
+
+	// Si le contenu contient des retours à la ligne ou dépasse la largeur, utiliser MultiCell
+	text := b.encode(content)
+	if lines := b.wrapLines(text, b.area.width); strings.Contains(content, "\n") || len(lines) > 1 {
+		if to > 0 {
+			text = strings.Join(lines[from:min(to, len(lines))], "\n")
+		}
+		b.pdf.MultiCell(b.area.width, height, text, border, align, fill)
 	} else {
-		b.pdf.CellFormat(maxWidth, height*1.5, b.tr(content), border, 1, align, fill, 0, "")
+		b.pdf.CellFormat(b.area.width, height*1.5, text, border, 1, align, fill, 0, "")
 	}
 }
 
@@ -613,13 +917,12 @@ func (b *PDFBuilder) renderLine(element Element) {
 
 	b.pdf.SetLineWidth(thickness)
 
-	currentX := b.pdf.GetX()
+	currentX := b.area.x
 	currentY := b.pdf.GetY()
 
 	endX := currentX + length
-	if length == 0 {
-		pageWidth, _ := b.pdf.GetPageSize()
-		endX = pageWidth - b.margins.right
+	if length == 0 || length > b.area.width {
+		endX = b.area.x + b.area.width
 	}
 
 	b.pdf.Line(currentX, currentY, endX, currentY)
@@ -631,55 +934,74 @@ func (b *PDFBuilder) renderLine(element Element) {
 
 func (b *PDFBuilder) renderImage(element Element) {
 	// Pour l'instant, on ne gère que les images base64
-	if str, ok := element.Content.(string); ok && strings.HasPrefix(str, "data:image/") {
-		// Extraire les données base64
-		parts := strings.Split(str, ",")
-		if len(parts) == 2 {
-			imageData, err := base64.StdEncoding.DecodeString(parts[1])
-			if err == nil {
-				// Créer un nom temporaire
-				imageName := fmt.Sprintf("temp_image_%d", len(imageData))
-
-				// Type d'image
-				imageType := "PNG"
-				if strings.Contains(parts[0], "jpeg") || strings.Contains(parts[0], "jpg") {
-					imageType = "JPG"
-				}
+	imageData, imageType, ok := decodeImageData(element.Content)
+	if !ok {
+		return
+	}
 
-				// Enregistrer temporairement l'image
-				b.pdf.RegisterImageReader(imageName, imageType, bytes.NewReader(imageData))
+	// Créer un nom temporaire et enregistrer l'image
+	imageName := fmt.Sprintf("temp_image_%d", len(imageData))
+	b.pdf.RegisterImageReader(imageName, imageType, bytes.NewReader(imageData))
 
-				// Dimensions
-				width := 50.0
-				height := 0.0 // auto
-				if element.Style != nil {
-					if element.Style.Width > 0 {
-						width = element.Style.Width
-					}
-					if element.Style.Height > 0 {
-						height = element.Style.Height
-					}
-				}
+	width, height := imageDimensions(element.Style, b.area.width)
+	b.pdf.ImageOptions(imageName, b.area.x, b.pdf.GetY(), width, height, false, gofpdf.ImageOptions{}, 0, "")
 
-				b.pdf.ImageOptions(imageName, b.pdf.GetX(), b.pdf.GetY(), width, height, false, gofpdf.ImageOptions{}, 0, "")
+	if height == 0 {
+		height = width * 0.75 // ratio par défaut
+	}
+	b.pdf.Ln(height + 2)
+}
 
-				if height == 0 {
-					height = width * 0.75 // ratio par défaut
-				}
-				b.pdf.Ln(height + 2)
-			}
-		}
+// decodeImageData extrait les données et le type (PNG/JPG) d'une image "data:image/...;base64,..."
+func decodeImageData(content interface{}) ([]byte, string, bool) {
+	str, ok := content.(string)
+	if !ok || !strings.HasPrefix(str, "data:image/") {
+		return nil, "", false
 	}
+
+	parts := strings.Split(str, ",")
+	if len(parts) != 2 {
+		return nil, "", false
+	}
+
+	imageData, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, "", false
+	}
+
+	imageType := "PNG"
+	if strings.Contains(parts[0], "jpeg") || strings.Contains(parts[0], "jpg") {
+		imageType = "JPG"
+	}
+	return imageData, imageType, true
 }
 
-func (b *PDFBuilder) Build() ([]byte, error) {
-	b.pdf.AddPage()
-	b.setupFonts()
+// imageDimensions retourne la taille d'une image (hauteur 0 = auto), réduite pour ne pas
+// dépasser la largeur disponible (colonne de grille)
+func imageDimensions(style *Style, maxWidth float64) (width, height float64) {
+	width = 50.0
+	if style != nil {
+		if style.Width > 0 {
+			width = style.Width
+		}
+		if style.Height > 0 {
+			height = style.Height
+		}
+	}
 
-	// Rendre tous les éléments
-	for _, element := range b.config.Elements {
-		b.renderElement(element)
+	if width > maxWidth {
+		if height > 0 {
+			height = height * maxWidth / width
+		}
+		width = maxWidth
 	}
+	return width, height
+}
+
+func (b *PDFBuilder) Build() ([]byte, error) {
+	// Mise en page puis dessin des boîtes positionnées
+	boxes := b.Layout()
+	b.paint(boxes)
 
 	var buf bytes.Buffer
 	if err := b.pdf.Output(&buf); err != nil {
@@ -808,6 +1130,11 @@ func (tp *TemplateProcessor) ProcessTemplate(templateContent []byte) ([]byte, er
 			return match
 		}
 
+		// Les numéros de page sont résolus au dessin, une fois la mise en page connue
+		if tp.isPageToken(varName) {
+			return match
+		}
+
 		// Gérer les champs imbriqués (ex: user.name)
 		value := tp.getNestedValue(varName)
 
@@ -905,6 +1232,9 @@ func (tp *TemplateProcessor) processLoop(arrayName, loopContent string) string {
 			re := regexp.MustCompile(`\{\{\s*([^}#/]+)\s*\}\}`)
 			processedLoopContent := re.ReplaceAllStringFunc(loopContent, func(varMatch string) string {
 				varName := strings.TrimSpace(strings.Trim(varMatch, "{}"))
+				if tempProcessor.isPageToken(varName) {
+					return varMatch
+				}
 				value := tempProcessor.getNestedValue(varName)
 				return tempProcessor.valueToString(value)
 			})
@@ -947,6 +1277,9 @@ func (tp *TemplateProcessor) processLoop(arrayName, loopContent string) string {
 			re := regexp.MustCompile(`\{\{\s*([^}#/]+)\s*\}\}`)
 			processedLoopContent := re.ReplaceAllStringFunc(loopContent, func(varMatch string) string {
 				varName := strings.TrimSpace(strings.Trim(varMatch, "{}"))
+				if tempProcessor.isPageToken(varName) {
+					return varMatch
+				}
 				value := tempProcessor.getNestedValue(varName)
 				return tempProcessor.valueToString(value)
 			})
@@ -966,26 +1299,42 @@ func (tp *TemplateProcessor) processLoop(arrayName, loopContent string) string {
 	return ""
 }
 
+// isPageToken indique si une variable est un numéro de page ({{pageNumber}}, {{totalPages}})
+// à conserver pour le dessin, sauf si elle est explicitement fournie
+func (tp *TemplateProcessor) isPageToken(varName string) bool {
+	if varName != "pageNumber" && varName != "totalPages" {
+		return false
+	}
+	_, provided := tp.variables[varName]
+	return !provided
+}
+
 // getNestedValue récupère une valeur potentiellement imbriquée
 func (tp *TemplateProcessor) getNestedValue(path string) interface{} {
-	parts := strings.Split(path, ".")
+	if value, ok := lookupValue(tp.variables, path); ok {
+		return value
+	}
+	return ""
+}
 
-	var current interface{} = tp.variables
+// lookupValue suit un chemin pointé (ex: "user.name") dans des maps imbriquées
+func lookupValue(root interface{}, path string) (interface{}, bool) {
+	current := root
 
-	for _, part := range parts {
+	for _, part := range strings.Split(path, ".") {
 		switch v := current.(type) {
 		case map[string]interface{}:
-			if val, exists := v[part]; exists {
-				current = val
-			} else {
-				return ""
+			val, exists := v[part]
+			if !exists {
+				return nil, false
 			}
+			current = val
 		default:
-			return ""
+			return nil, false
 		}
 	}
 
-	return current
+	return current, true
 }
 
 // valueToString convertit une valeur en string pour l'insertion dans le JSON
@@ -1085,7 +1434,7 @@ func GeneratePDFFromFile(templatePath string, variables map[string]interface{})
 		return nil, err
 	}
 
-	return GeneratePDF(template)
+	return GeneratePDFWithVariables(template, variables)
 }
 
 // GeneratePDFFromContent génère un PDF depuis un contenu template avec des variables
@@ -1095,11 +1444,30 @@ func GeneratePDFFromContent(templateContent []byte, variables map[string]interfa
 		return nil, err
 	}
 
-	return GeneratePDF(template)
+	return GeneratePDFWithVariables(template, variables)
 }
 
 // Fonction principale pour générer un PDF depuis un template JSON
 func GeneratePDF(template Template) ([]byte, error) {
+	return GeneratePDFWithVariables(template, nil)
+}
+
+// GeneratePDFWithVariables génère un PDF depuis un template déjà traité, en gardant
+// les variables accessibles pour les tableaux liés aux données (dataSource)
+func GeneratePDFWithVariables(template Template, variables map[string]interface{}) ([]byte, error) {
 	builder := NewPDFBuilder(template)
+	builder.vars = variables
 	return builder.Build()
 }
+
+// GeneratePDFWithFontReport génère un PDF comme GeneratePDFWithVariables et liste les polices
+// incluses avec leur taille
+func GeneratePDFWithFontReport(template Template, variables map[string]interface{}) ([]byte, []EmbeddedFont, error) {
+	builder := NewPDFBuilder(template)
+	builder.vars = variables
+	pdf, err := builder.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	return pdf, builder.FontReport(pdf), nil
+}
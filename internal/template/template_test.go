@@ -0,0 +1,76 @@
+package template
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestFitColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		widths []float64
+		width  float64
+		want   []float64
+	}{
+		{"tient dans la zone", []float64{40, 60}, 180, []float64{40, 60}},
+		{"largeur exacte", []float64{40, 60}, 100, []float64{40, 60}},
+		{"réduites proportionnellement", []float64{60, 40}, 30, []float64{18, 12}},
+		{"zone sans largeur", []float64{60, 40}, 0, []float64{60, 40}},
+	}
+	for _, test := range tests {
+		var columns []TableColumn
+		for _, w := range test.widths {
+			columns = append(columns, TableColumn{Header: "Colonne", Width: w})
+		}
+		fitted := fitColumns(columns, test.width)
+		for c, col := range fitted {
+			if col.Width != test.want[c] || col.Header != "Colonne" {
+				t.Errorf("%s : colonne %d de %v mm, attendu %v mm", test.name, c, col.Width, test.want[c])
+			}
+		}
+		if columns[0].Width != test.widths[0] {
+			t.Errorf("%s : colonnes d'origine modifiées", test.name)
+		}
+	}
+}
+
+// rectRe reconnaît les rectangles (bordures de cellules) d'un flux PDF non compressé : x y w h re
+var rectRe = regexp.MustCompile(`([\d.-]+) ([\d.-]+) ([\d.-]+) ([\d.-]+) re`)
+
+func TestTableInNarrowGridColumn(t *testing.T) {
+	template := Template{Elements: []Element{{
+		Type:         "grid",
+		ColumnWidths: []interface{}{30.0, "1fr"},
+		Children: []Element{
+			{Type: "table", Columns: []TableColumn{{Header: "Article", Width: 60}, {Header: "Prix", Width: 40}},
+				Rows: []TableRow{{Cells: []string{"Stylo", "2,50"}}}},
+			{Type: "text", Content: "Colonne voisine"},
+		},
+	}}}
+	builder := NewPDFBuilder(template)
+	builder.pdf.SetCompression(false)
+	pdf, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bordures des cellules : toutes dans la colonne de 30 mm (marge gauche de 15 mm)
+	k := 72 / 25.4
+	right := (15 + 30) * k
+	rects := rectRe.FindAllSubmatch(pdf, -1)
+	if len(rects) != 4 {
+		t.Fatalf("%d cellule(s) bordée(s), attendu 4", len(rects))
+	}
+	for _, rect := range rects {
+		x, _ := strconv.ParseFloat(string(rect[1]), 64)
+		w, _ := strconv.ParseFloat(string(rect[3]), 64)
+		if x < 15*k-0.01 || x+w > right+0.01 {
+			t.Errorf("cellule de %.1f à %.1f pt, hors de la colonne [%.1f, %.1f]", x, x+w, 15*k, right)
+		}
+	}
+	if !bytes.Contains(pdf, []byte("Colonne voisine")) {
+		t.Error("texte de la colonne voisine absent")
+	}
+}
@@ -0,0 +1,420 @@
+package template
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// --- Décodage WOFF2 ---
+//
+// Un fichier WOFF2 contient un seul flux brotli avec toutes les tables, à la suite. Les
+// tables glyf et loca y sont en général transformées (contours répartis en flux séparés,
+// coordonnées en triplets) et hmtx peut l'être (approches gauches déduites de glyf) : elles
+// sont reconstruites au format TrueType (spécification W3C WOFF2, section 5).
+
+// woff2KnownTags sont les tags désignés par leur numéro dans le répertoire des tables
+var woff2KnownTags = [63]string{
+	"cmap", "head", "hhea", "hmtx", "maxp", "name", "OS/2", "post", "cvt ", "fpgm",
+	"glyf", "loca", "prep", "CFF ", "VORG", "EBDT", "EBLC", "gasp", "hdmx", "kern",
+	"LTSH", "PCLT", "VDMX", "vhea", "vmtx", "BASE", "GDEF", "GPOS", "GSUB", "EBSC",
+	"JSTF", "MATH", "CBDT", "CBLC", "COLR", "CPAL", "SVG ", "sbix", "acnt", "avar",
+	"bdat", "bloc", "bsln", "cvar", "fdsc", "feat", "fmtx", "fvar", "gvar", "hsty",
+	"just", "lcar", "mort", "morx", "opbd", "prop", "trak", "Zapf", "Silf", "Glat",
+	"Gloc", "Feat", "Sill",
+}
+
+// woff2Table est une entrée du répertoire des tables d'un fichier WOFF2
+type woff2Table struct {
+	tag         string
+	transformed bool
+	length      uint32 // Longueur dans le flux décompressé
+}
+
+// woff2Reader lit les types de données propres à WOFF2
+type woff2Reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *woff2Reader) fail(format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf(format, args...)
+	}
+}
+
+// bytes lit n octets ; nil si les données sont tronquées (l'erreur est gardée dans r.err)
+func (r *woff2Reader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data)-r.pos {
+		r.fail("données tronquées")
+		return nil
+	}
+	r.pos += n
+	return r.data[r.pos-n : r.pos]
+}
+
+// uint lit un entier non signé de n octets (big-endian), 0 si les données sont tronquées
+func (r *woff2Reader) uint(n int) uint32 {
+	var value uint32
+	for _, b := range r.bytes(n) {
+		value = value<<8 | uint32(b)
+	}
+	return value
+}
+
+func (r *woff2Reader) u8() int     { return int(r.uint(1)) }
+func (r *woff2Reader) u16() int    { return int(r.uint(2)) }
+func (r *woff2Reader) i16() int    { return int(int16(r.uint(2))) }
+func (r *woff2Reader) u32() uint32 { return r.uint(4) }
+
+// base128 lit un UIntBase128 (entier de 1 à 5 octets, 7 bits par octet)
+func (r *woff2Reader) base128() uint32 {
+	var value uint32
+	for i := 0; i < 5; i++ {
+		b := r.u8()
+		if i == 0 && b == 0x80 || value&0xFE000000 != 0 {
+			r.fail("UIntBase128 invalide")
+			return 0
+		}
+		value = value<<7 | uint32(b&0x7F)
+		if b&0x80 == 0 {
+			return value
+		}
+	}
+	r.fail("UIntBase128 trop long")
+	return 0
+}
+
+// u255 lit un 255UInt16 (entier de 1 à 3 octets)
+func (r *woff2Reader) u255() int {
+	switch code := r.u8(); code {
+	case 253:
+		return r.u16()
+	case 254:
+		return r.u8() + 253*2
+	case 255:
+		return r.u8() + 253
+	default:
+		return code
+	}
+}
+
+// decodeWOFF2 reconstitue la police sfnt d'un fichier WOFF2
+func decodeWOFF2(data []byte) ([]byte, error) {
+	r := &woff2Reader{data: data}
+	r.u32() // signature
+	flavor := r.u32()
+	r.u32() // length
+	count := r.u16()
+	r.u16() // reserved
+	totalSfntSize := r.u32()
+	compressedSize := r.u32()
+	r.bytes(24) // version, métadonnées et données privées
+	if flavor == sfntCollection {
+		return nil, fmt.Errorf("les collections de polices (ttc) ne sont pas gérées")
+	}
+	if totalSfntSize > maxFontSize {
+		return nil, fmt.Errorf("police de %d octets, au plus %d", totalSfntSize, maxFontSize)
+	}
+
+	entries := make([]woff2Table, count)
+	for i := range entries {
+		flags := r.u8()
+		entry := &entries[i]
+		if flags&0x3F == 0x3F {
+			entry.tag = string(r.bytes(4))
+		} else {
+			entry.tag = woff2KnownTags[flags&0x3F]
+		}
+		entry.length = r.base128()
+
+		// glyf et loca sont transformées en version 0, les autres tables en version non nulle
+		version := flags >> 6
+		if entry.tag == "glyf" || entry.tag == "loca" {
+			entry.transformed = version == 0
+		} else {
+			entry.transformed = version != 0
+		}
+		if entry.transformed {
+			entry.length = r.base128()
+		}
+	}
+	compressed := r.bytes(int(compressedSize))
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// Le flux décompressé ne peut pas dépasser la taille de la police annoncée dans l'en-tête
+	brotliReader := brotli.NewReader(bytes.NewReader(compressed))
+	stream, err := io.ReadAll(io.LimitReader(brotliReader, int64(totalSfntSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(stream) > int(totalSfntSize) {
+		return nil, fmt.Errorf("flux décompressé de plus de %d octets (totalSfntSize)", totalSfntSize)
+	}
+
+	var tables []sfntTable
+	transformed := map[string][]byte{}
+	offset := uint64(0)
+	for _, entry := range entries {
+		if offset+uint64(entry.length) > uint64(len(stream)) {
+			return nil, fmt.Errorf("table %q hors du flux", entry.tag)
+		}
+		table := stream[offset : offset+uint64(entry.length)]
+		offset += uint64(entry.length)
+		if entry.transformed {
+			transformed[entry.tag] = table
+			continue
+		}
+		tables = append(tables, sfntTable{entry.tag, table})
+	}
+
+	// Tables transformées : glyf et loca, puis hmtx qui dépend des contours
+	var xMins []int
+	if glyf, ok := transformed["glyf"]; ok {
+		glyphs, mins, err := decodeWOFF2Glyf(glyf)
+		if err != nil {
+			return nil, fmt.Errorf("glyf : %w", err)
+		}
+		head, err := withLongLoca(findTable(tables, "head"))
+		if err != nil {
+			return nil, err
+		}
+		glyfData, locaData := glyfTables(glyphs)
+		tables = replaceTable(tables, "head", head)
+		tables = append(tables, sfntTable{"glyf", glyfData}, sfntTable{"loca", locaData})
+		xMins = mins
+	}
+	if hmtx, ok := transformed["hmtx"]; ok {
+		table, err := decodeWOFF2Hmtx(hmtx, findTable(tables, "hhea"), xMins)
+		if err != nil {
+			return nil, fmt.Errorf("hmtx : %w", err)
+		}
+		tables = append(tables, sfntTable{"hmtx", table})
+	}
+	return writeSFNT(flavor, tables), nil
+}
+
+// replaceTable remplace (ou ajoute) une table
+func replaceTable(tables []sfntTable, tag string, data []byte) []sfntTable {
+	for i := range tables {
+		if tables[i].tag == tag {
+			tables[i].data = data
+			return tables
+		}
+	}
+	return append(tables, sfntTable{tag, data})
+}
+
+// decodeWOFF2Glyf reconstitue les glyphes d'une table glyf transformée, avec le xMin de chacun
+// (pour hmtx)
+func decodeWOFF2Glyf(data []byte) ([][]byte, []int, error) {
+	header := &woff2Reader{data: data}
+	header.u16() // reserved
+	options := header.u16()
+	numGlyphs := header.u16()
+	header.u16() // indexFormat : la table loca reconstruite est toujours au format long
+	var sizes [7]int
+	for i := range sizes {
+		sizes[i] = int(header.u32())
+	}
+	if header.err != nil {
+		return nil, nil, header.err
+	}
+
+	// Flux : nombres de contours, nombres de points, drapeaux, coordonnées, composites,
+	// boîtes englobantes, instructions, puis bitmap des contours superposés (optionnel)
+	streams := make([]*woff2Reader, 7)
+	for i, size := range sizes {
+		streams[i] = &woff2Reader{data: header.bytes(size)}
+	}
+	contourStream, pointStream, flagStream, glyphStream := streams[0], streams[1], streams[2], streams[3]
+	compositeStream, bboxStream, instructionStream := streams[4], streams[5], streams[6]
+	bitmapSize := ((numGlyphs + 31) >> 5) << 2
+	bboxBitmap := bboxStream.bytes(bitmapSize)
+	var overlapBitmap []byte
+	if options&1 != 0 {
+		overlapBitmap = header.bytes((numGlyphs + 7) >> 3)
+	}
+	if header.err != nil {
+		return nil, nil, header.err
+	}
+
+	hasBit := func(bitmap []byte, i int) bool {
+		return bitmap != nil && bitmap[i>>3]&(0x80>>(uint(i)&7)) != 0
+	}
+	readBBox := func() *[4]int {
+		return &[4]int{bboxStream.i16(), bboxStream.i16(), bboxStream.i16(), bboxStream.i16()}
+	}
+
+	glyphs := make([][]byte, numGlyphs)
+	xMins := make([]int, numGlyphs)
+	for i := 0; i < numGlyphs; i++ {
+		contours := int(int16(contourStream.u16()))
+		switch {
+		case contours == 0:
+			// Glyphe vide
+
+		case contours < 0:
+			// Glyphe composé : composants copiés tels quels, boîte englobante obligatoire
+			if !hasBit(bboxBitmap, i) {
+				return nil, nil, fmt.Errorf("glyphe composé %d sans boîte englobante", i)
+			}
+			bbox := readBBox()
+			var glyph bytes.Buffer
+			glyph.Write([]byte{0xFF, 0xFF})
+			for _, value := range bbox {
+				glyph.Write([]byte{byte(value >> 8), byte(value)})
+			}
+			start := compositeStream.pos
+			instructions := false
+			for more := true; more; {
+				flags := compositeStream.u16()
+				compositeStream.u16() // glyphe du composant
+				size := 2
+				if flags&0x0001 != 0 {
+					size = 4
+				}
+				switch {
+				case flags&0x0008 != 0:
+					size += 2
+				case flags&0x0040 != 0:
+					size += 4
+				case flags&0x0080 != 0:
+					size += 8
+				}
+				compositeStream.bytes(size)
+				instructions = instructions || flags&0x0100 != 0
+				more = flags&0x0020 != 0
+			}
+			glyph.Write(compositeStream.data[start:compositeStream.pos])
+			if instructions {
+				length := glyphStream.u255()
+				glyph.Write([]byte{byte(length >> 8), byte(length)})
+				glyph.Write(instructionStream.bytes(length))
+			}
+			glyphs[i] = glyph.Bytes()
+			xMins[i] = bbox[0]
+
+		default:
+			// Glyphe simple : points en triplets (drapeau + 1 à 4 octets de coordonnées)
+			// Un drapeau par point : le nombre de points est borné par le flux des drapeaux
+			points := make([][]glyphPoint, contours)
+			flags := len(flagStream.data) - flagStream.pos
+			for c := range points {
+				count := pointStream.u255()
+				if pointStream.err != nil {
+					return nil, nil, fmt.Errorf("glyphe %d : %w", i, pointStream.err)
+				}
+				if flags -= count; flags < 0 {
+					return nil, nil, fmt.Errorf("glyphe %d : plus de points que de drapeaux", i)
+				}
+				points[c] = make([]glyphPoint, count)
+			}
+			x, y := 0, 0
+			for _, contour := range points {
+				for p := range contour {
+					flag := flagStream.u8()
+					dx, dy := decodeTriplet(flag&0x7F, glyphStream)
+					x, y = x+dx, y+dy
+					contour[p] = glyphPoint{x, y, flag&0x80 == 0}
+				}
+			}
+			instructions := instructionStream.bytes(glyphStream.u255())
+
+			var bbox *[4]int
+			if hasBit(bboxBitmap, i) {
+				bbox = readBBox()
+			}
+			glyphs[i] = encodeSimpleGlyph(points, instructions, bbox, hasBit(overlapBitmap, i))
+			if len(glyphs[i]) >= 4 {
+				xMins[i] = int(int16(binary.BigEndian.Uint16(glyphs[i][2:])))
+			}
+		}
+
+		for _, stream := range streams {
+			if stream.err != nil {
+				return nil, nil, fmt.Errorf("glyphe %d : %w", i, stream.err)
+			}
+		}
+	}
+	return glyphs, xMins, nil
+}
+
+// decodeTriplet lit le déplacement d'un point codé en triplet (drapeau sur 7 bits)
+func decodeTriplet(flag int, r *woff2Reader) (int, int) {
+	withSign := func(flag, value int) int {
+		if flag&1 != 0 {
+			return value
+		}
+		return -value
+	}
+
+	switch {
+	case flag < 10:
+		return 0, withSign(flag, (flag&14)<<7+r.u8())
+	case flag < 20:
+		return withSign(flag, ((flag-10)&14)<<7+r.u8()), 0
+	case flag < 84:
+		b0, b1 := flag-20, r.u8()
+		return withSign(flag, 1+(b0&0x30)+b1>>4), withSign(flag>>1, 1+(b0&0x0C)<<2+b1&0x0F)
+	case flag < 120:
+		b0 := flag - 84
+		b1, b2 := r.u8(), r.u8()
+		return withSign(flag, 1+(b0/12)<<8+b1), withSign(flag>>1, 1+((b0%12)>>2)<<8+b2)
+	case flag < 124:
+		b1, b2, b3 := r.u8(), r.u8(), r.u8()
+		return withSign(flag, b1<<4+b2>>4), withSign(flag>>1, (b2&0x0F)<<8+b3)
+	default:
+		b1, b2, b3, b4 := r.u8(), r.u8(), r.u8(), r.u8()
+		return withSign(flag, b1<<8+b2), withSign(flag>>1, b3<<8+b4)
+	}
+}
+
+// decodeWOFF2Hmtx reconstitue une table hmtx transformée : les approches gauches omises sont
+// les xMin des glyphes
+func decodeWOFF2Hmtx(data, hhea []byte, xMins []int) ([]byte, error) {
+	if len(hhea) < 36 {
+		return nil, fmt.Errorf("table hhea absente")
+	}
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	numGlyphs := len(xMins)
+	if metrics > numGlyphs {
+		return nil, fmt.Errorf("table glyf absente ou incomplète")
+	}
+
+	r := &woff2Reader{data: data}
+	flags := r.u8()
+	advances := make([]int, metrics)
+	for i := range advances {
+		advances[i] = r.u16()
+	}
+	lsbs := append([]int(nil), xMins...)
+	if flags&1 == 0 {
+		for i := 0; i < metrics; i++ {
+			lsbs[i] = r.i16()
+		}
+	}
+	if flags&2 == 0 {
+		for i := metrics; i < numGlyphs; i++ {
+			lsbs[i] = r.i16()
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	table := make([]byte, 0, 4*metrics+2*(numGlyphs-metrics))
+	for i := 0; i < numGlyphs; i++ {
+		if i < metrics {
+			table = binary.BigEndian.AppendUint16(table, uint16(advances[i]))
+		}
+		table = binary.BigEndian.AppendUint16(table, uint16(lsbs[i]))
+	}
+	return table, nil
+}
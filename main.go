@@ -4,6 +4,7 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -19,6 +20,10 @@ type InputData struct {
 }
 
 func main() {
+	// -font-report : écrire sur stderr la taille des polices incluses dans le PDF
+	fontReport := flag.Bool("font-report", false, "écrire sur stderr la taille des polices incluses")
+	flag.Parse()
+
 	// Lire JSON depuis stdin
 	in, err := io.ReadAll(os.Stdin)
 	if err != nil {
@@ -33,6 +38,7 @@ func main() {
 	}
 
 	var pdfBytes []byte
+	var fonts []template.EmbeddedFont
 
 	// Nouveau format avec template + variables
 	if input.PdfTemplate != nil {
@@ -44,20 +50,27 @@ func main() {
 		}
 
 		// Traiter le template avec les variables
-		pdfBytes, err = template.GeneratePDFFromContent(templateBytes, input.PdfVars)
+		processed, err := template.ProcessTemplateContent(templateBytes, input.PdfVars)
+		if err == nil {
+			pdfBytes, fonts, err = template.GeneratePDFWithFontReport(processed, input.PdfVars)
+		}
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "pdf generation error:", err)
 			os.Exit(1)
 		}
 	} else {
 		// Ancien format (template direct) - compatibilité ascendante
-		pdfBytes, err = template.GeneratePDF(input.Template)
+		pdfBytes, fonts, err = template.GeneratePDFWithFontReport(input.Template, nil)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "pdf error:", err)
 			os.Exit(1)
 		}
 	}
 
+	if *fontReport {
+		template.WriteFontReport(os.Stderr, fonts)
+	}
+
 	// Écrire le PDF binaire sur stdout
 
 	if _, err := os.Stdout.Write(pdfBytes); err != nil {